@@ -1,6 +1,6 @@
 module github.com/joincivil/go-common-priv
 
-go 1.12
+go 1.15
 
 require (
 	cloud.google.com/go v0.46.3 // indirect
@@ -15,7 +15,7 @@ require (
 	github.com/jinzhu/gorm v1.9.10
 	github.com/joincivil/go-common v0.0.0-20190925152827-26fccd64f4e8
 	github.com/karalabe/usb v0.0.0-20191104083709-911d15fe12a9 // indirect
-	github.com/lib/pq v1.2.0 // indirect
+	github.com/lib/pq v1.2.0
 	github.com/mattn/go-sqlite3 v1.11.0 // indirect
 	github.com/pkg/errors v0.8.1
 	github.com/steakknife/bloomfilter v0.0.0-20180922174646-6819c0d2a570 // indirect
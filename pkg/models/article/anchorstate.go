@@ -0,0 +1,170 @@
+package article
+
+import (
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+	carticle "github.com/joincivil/go-common/pkg/article"
+	"github.com/pkg/errors"
+
+	"github.com/joincivil/go-common-priv/pkg/models/migrations"
+)
+
+// AnchorState is where an article is in the content claim pipeline
+type AnchorState string
+
+const (
+	// AnchorStatePending is an article that has not been submitted for a claim yet
+	AnchorStatePending AnchorState = "pending"
+	// AnchorStateSubmitted is an article with a claim transaction that has not been mined yet
+	AnchorStateSubmitted AnchorState = "submitted"
+	// AnchorStateConfirmed is an article with a mined claim transaction receipt
+	AnchorStateConfirmed AnchorState = "confirmed"
+	// AnchorStateFailed is an article whose claim transaction failed
+	AnchorStateFailed AnchorState = "failed"
+	// AnchorStateUnanchored is an article whose claim was in a block that was reorged out
+	AnchorStateUnanchored AnchorState = "unanchored"
+)
+
+// anchorStateTransitions are the valid moves from each anchor state. Any state but
+// confirmed can move to confirmed since a receipt can be found for a transaction
+// that was not tracked as submitted.
+var anchorStateTransitions = map[AnchorState][]AnchorState{
+	AnchorStatePending:    {AnchorStateSubmitted, AnchorStateConfirmed, AnchorStateFailed},
+	AnchorStateSubmitted:  {AnchorStateConfirmed, AnchorStateFailed},
+	AnchorStateConfirmed:  {AnchorStateUnanchored},
+	AnchorStateFailed:     {AnchorStatePending, AnchorStateSubmitted, AnchorStateConfirmed},
+	AnchorStateUnanchored: {AnchorStatePending, AnchorStateSubmitted, AnchorStateConfirmed},
+}
+
+// InvalidTransitionError is returned for a move between anchor states that is not allowed
+type InvalidTransitionError struct {
+	From AnchorState
+	To   AnchorState
+}
+
+// Error implements the error interface
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("invalid anchor state transition from %v to %v", e.From, e.To)
+}
+
+// IsValid returns true if the state is one of the known anchor states
+func (s AnchorState) IsValid() bool {
+	_, ok := anchorStateTransitions[s]
+	return ok
+}
+
+// CanTransitionTo returns true if moving from this state to the given state is allowed
+func (s AnchorState) CanTransitionTo(to AnchorState) bool {
+	for _, valid := range anchorStateTransitions[s] {
+		if valid == to {
+			return true
+		}
+	}
+	return false
+}
+
+// BeforeCreate sets the initial anchor state of a new article. Articles created with
+// block data are confirmed.
+func (a *Gorm) BeforeCreate() error {
+	if a.AnchorState == "" {
+		a.AnchorState = AnchorStatePending
+		if a.TxHash != "" {
+			a.AnchorState = AnchorStateConfirmed
+		}
+	}
+	return nil
+}
+
+// TransitionTo moves the article to the given anchor state. Returns an
+// InvalidTransitionError if the move is not allowed.
+func (a *Gorm) TransitionTo(to AnchorState) error {
+	from := a.AnchorState
+	if from == "" {
+		from = AnchorStatePending
+	}
+	if !from.CanTransitionTo(to) {
+		return &InvalidTransitionError{From: from, To: to}
+	}
+	a.AnchorState = to
+	return nil
+}
+
+// ArticleAnchorState returns the anchor state of the article with the given ID
+func (p *GormPGPersister) ArticleAnchorState(articleID uint) (AnchorState, error) {
+	articleGorm := &Gorm{}
+	if err := p.DB.Select("id, anchor_state").First(articleGorm, articleID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return "", ErrArticleNotFound
+		}
+		return "", err
+	}
+	return articleGorm.AnchorState, nil
+}
+
+// TransitionArticleState moves the article with the given ID to the given anchor state.
+// Returns an InvalidTransitionError if the move is not allowed.
+func (p *GormPGPersister) TransitionArticleState(articleID uint, to AnchorState) error {
+	return withTransaction(p.DB, func(tx *gorm.DB) error {
+		articleGorm, err := lockArticle(tx, articleID)
+		if err != nil {
+			return err
+		}
+		if err := articleGorm.TransitionTo(to); err != nil {
+			return err
+		}
+		return tx.Model(articleGorm).Update("anchor_state", articleGorm.AnchorState).Error
+	})
+}
+
+// ArticlesInState returns the articles in the given anchor state. Filters by newsroom
+// if newsroomAddress is not empty.
+func (p *GormPGPersister) ArticlesInState(newsroomAddress string, state AnchorState) ([]carticle.Article, error) {
+	db := p.DB.Where("anchor_state = ?", state)
+	if newsroomAddress != "" {
+		db = db.Where("newsroom_address = ?", NormalizeNewsroomAddress(newsroomAddress))
+	}
+
+	articleGorms := []Gorm{}
+	if err := db.Order("id ASC").Find(&articleGorms).Error; err != nil {
+		return nil, err
+	}
+
+	return convertArticleGorms(articleGorms)
+}
+
+// BackfillAnchorStates sets the anchor state of articles saved before the state existed
+// by applying the models migrations.
+//
+// Deprecated: use migrations.Up, which backfills the states when it adds them.
+func (p *GormPGPersister) BackfillAnchorStates() error {
+	return migrations.Up(p.DB)
+}
+
+// confirmAnchorState moves the article to confirmed if it is not already
+func confirmAnchorState(tx *gorm.DB, articleID uint) error {
+	articleGorm, err := lockArticle(tx, articleID)
+	if err != nil {
+		return err
+	}
+	if articleGorm.AnchorState == AnchorStateConfirmed {
+		return nil
+	}
+	if err := articleGorm.TransitionTo(AnchorStateConfirmed); err != nil {
+		return err
+	}
+	return tx.Model(articleGorm).Update("anchor_state", articleGorm.AnchorState).Error
+}
+
+// lockArticle selects the article for update
+func lockArticle(tx *gorm.DB, articleID uint) (*Gorm, error) {
+	articleGorm := &Gorm{}
+	err := tx.Set("gorm:query_option", "FOR UPDATE").First(articleGorm, articleID).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrArticleNotFound
+		}
+		return nil, errors.Wrap(err, "error locking article")
+	}
+	return articleGorm, nil
+}
@@ -0,0 +1,136 @@
+package article_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/joincivil/go-common-priv/pkg/models/article"
+	"github.com/joincivil/go-common-priv/pkg/models/testutils"
+	carticle "github.com/joincivil/go-common/pkg/article"
+)
+
+func TestAnchorStateTransitions(t *testing.T) {
+	articleGorm := &article.Gorm{}
+
+	if err := articleGorm.TransitionTo(article.AnchorStateSubmitted); err != nil {
+		t.Errorf("should be able to submit a new article: err: %v", err)
+	}
+	if err := articleGorm.TransitionTo(article.AnchorStatePending); err == nil {
+		t.Errorf("should not be able to move a submitted article back to pending")
+	}
+	if err := articleGorm.TransitionTo(article.AnchorStateConfirmed); err != nil {
+		t.Errorf("should be able to confirm a submitted article: err: %v", err)
+	}
+
+	err := articleGorm.TransitionTo(article.AnchorStateFailed)
+	transitionErr, ok := err.(*article.InvalidTransitionError)
+	if !ok {
+		t.Fatalf("should have returned an InvalidTransitionError: err: %v", err)
+	}
+	if transitionErr.From != article.AnchorStateConfirmed || transitionErr.To != article.AnchorStateFailed {
+		t.Errorf("should have the states of the invalid transition: %v", transitionErr)
+	}
+	if articleGorm.AnchorState != article.AnchorStateConfirmed {
+		t.Errorf("should not have changed the state after an invalid transition")
+	}
+
+	if err := articleGorm.TransitionTo(article.AnchorStateUnanchored); err != nil {
+		t.Errorf("should be able to unanchor a confirmed article: err: %v", err)
+	}
+	if err := articleGorm.TransitionTo(article.AnchorStateSubmitted); err != nil {
+		t.Errorf("should be able to resubmit an unanchored article: err: %v", err)
+	}
+
+	if article.AnchorState("notastate").IsValid() {
+		t.Errorf("should not be a valid state")
+	}
+}
+
+func TestTransitionArticleState(t *testing.T) {
+	creds := testutils.GetTestDBConnection()
+	pg, err := article.NewGormPGPersister(creds.Host, creds.Port, creds.User, creds.Password, creds.Dbname)
+
+	if err != nil {
+		fmt.Println(err)
+		t.Errorf("threw an error making the persister")
+	}
+
+	testutils.MigrateModels(pg.DB) // nolint: errcheck
+
+	defer pg.DB.Close()
+
+	cleaner := testutils.DeleteCreatedEntities(pg.DB)
+	defer cleaner()
+
+	newsroomAddr := "0x8c722B8AC728aDd7780a66017e8daDBa530EE261"
+	narticle := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Title:        "claimed stuff",
+			CanonicalURL: "https://newstuff.bz/claimedarticle",
+		},
+		NewsroomAddress: newsroomAddr,
+	}
+
+	if err := pg.CreateArticle(narticle); err != nil {
+		t.Fatalf("should have created article: err: %v", err)
+	}
+
+	state, err := pg.ArticleAnchorState(narticle.ID)
+	if err != nil {
+		t.Errorf("should have retrieved the anchor state: err: %v", err)
+	}
+	if state != article.AnchorStatePending {
+		t.Errorf("new article should be pending: state: %v", state)
+	}
+
+	pending, err := pg.ArticlesInState(newsroomAddr, article.AnchorStatePending)
+	if err != nil {
+		t.Errorf("should have retrieved articles in state: err: %v", err)
+	}
+	if len(pending) != 1 {
+		t.Errorf("should have retrieved the pending article: len: %v", len(pending))
+	}
+
+	if err := pg.TransitionArticleState(narticle.ID, article.AnchorStateSubmitted); err != nil {
+		t.Errorf("should have submitted the article: err: %v", err)
+	}
+	if err := pg.TransitionArticleState(narticle.ID, article.AnchorStatePending); err == nil {
+		t.Errorf("should not have moved the submitted article back to pending")
+	}
+
+	// Saving the receipt confirms the article
+	narticle.BlockData = testutils.MakeFakeReceipt()
+	if err := pg.UpdateArticle(narticle); err != nil {
+		t.Errorf("should have updated article: err: %v", err)
+	}
+
+	state, err = pg.ArticleAnchorState(narticle.ID)
+	if err != nil {
+		t.Errorf("should have retrieved the anchor state: err: %v", err)
+	}
+	if state != article.AnchorStateConfirmed {
+		t.Errorf("article with a receipt should be confirmed: state: %v", state)
+	}
+
+	// Updating the article without a receipt does not change the state
+	narticle.BlockData = carticle.Article{}.BlockData
+	if err := pg.UpdateArticle(narticle); err != nil {
+		t.Errorf("should have updated article: err: %v", err)
+	}
+	state, _ = pg.ArticleAnchorState(narticle.ID)
+	if state != article.AnchorStateConfirmed {
+		t.Errorf("should have kept the state on update: state: %v", state)
+	}
+
+	submitted, err := pg.ArticlesInState(newsroomAddr, article.AnchorStateSubmitted)
+	if err != nil {
+		t.Errorf("should have retrieved articles in state: err: %v", err)
+	}
+	if len(submitted) != 0 {
+		t.Errorf("should not have any submitted articles: len: %v", len(submitted))
+	}
+
+	if err := pg.TransitionArticleState(0, article.AnchorStateSubmitted); err != article.ErrArticleNotFound {
+		t.Errorf("should have returned ErrArticleNotFound: err: %v", err)
+	}
+}
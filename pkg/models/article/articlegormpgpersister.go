@@ -3,6 +3,7 @@ package article
 import (
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"time"
 
 	ethCommon "github.com/ethereum/go-ethereum/common"
@@ -10,14 +11,17 @@ import (
 	"github.com/jinzhu/gorm"
 	"github.com/jinzhu/gorm/dialects/postgres"
 	carticle "github.com/joincivil/go-common/pkg/article"
+	ceth "github.com/joincivil/go-common/pkg/eth"
 	"github.com/pkg/errors"
+
+	"github.com/joincivil/go-common-priv/pkg/models/internal/listcursor"
+	"github.com/joincivil/go-common-priv/pkg/models/migrations"
+	gormutils "github.com/joincivil/go-common-priv/pkg/utils/gorm"
 )
 
-const (
-	// Could make this configurable later if needed
-	maxOpenConns    = 5
-	maxIdleConns    = 3
-	connMaxLifetime = time.Second * 1800 // 30 mins
+var (
+	// ErrArticleNotFound indicates that no article was found for the query
+	ErrArticleNotFound = errors.New("article not found")
 )
 
 // Gorm is the article schema
@@ -28,6 +32,16 @@ type Gorm struct {
 	NewsroomAddress  string
 	IndexedTimestamp time.Time
 	RawJSON          postgres.Jsonb `gorm:"column:raw_json"`
+	// RawJSONText is the raw json as it was saved. jsonb reorders keys and drops
+	// whitespace, so content hashes are verified against this column.
+	RawJSONText string `gorm:"column:raw_json_text;type:text"`
+	// TxHash, BlockNumber, BlockHash and TxStatus are copied from BlockData
+	TxHash      string `gorm:"index"`
+	BlockNumber uint64 `gorm:"index"`
+	BlockHash   string
+	TxStatus    uint64
+	// AnchorState is where the article is in the content claim pipeline
+	AnchorState AnchorState `gorm:"index;default:'pending'"`
 }
 
 // TableName sets the name of the corresponding table in the db
@@ -35,12 +49,28 @@ func (Gorm) TableName() string {
 	return "articles"
 }
 
+// NormalizeNewsroomAddress returns the checksum cased address articles are saved and
+// queried with, as with newsrooms. An empty address stays empty.
+func NormalizeNewsroomAddress(address string) string {
+	if address == "" {
+		return ""
+	}
+	return ceth.NormalizeEthAddress(address)
+}
+
 // ConvertToArticle returns the gorm struct as the public article struct
 func (a *Gorm) ConvertToArticle() (*carticle.Article, error) {
 	article := &carticle.Article{}
 	// if it fails it probably hasnt been added yet, do nothing
 	blockdata := ethTypes.Receipt{}
 	if err := json.Unmarshal(a.BlockData.RawMessage, &blockdata); err == nil {
+		// fill in block data that only made it into the columns
+		if blockdata.BlockNumber == nil && a.BlockNumber != 0 {
+			blockdata.BlockNumber = new(big.Int).SetUint64(a.BlockNumber)
+		}
+		if blockdata.BlockHash == (ethCommon.Hash{}) && a.BlockHash != "" {
+			blockdata.BlockHash = ethCommon.HexToHash(a.BlockHash)
+		}
 		article.BlockData = blockdata
 	}
 
@@ -51,6 +81,9 @@ func (a *Gorm) ConvertToArticle() (*carticle.Article, error) {
 	}
 
 	article.RawJSON = a.RawJSON.RawMessage
+	if a.RawJSONText != "" {
+		article.RawJSON = json.RawMessage(a.RawJSONText)
+	}
 	article.ID = a.ID
 	article.NewsroomAddress = a.NewsroomAddress
 	article.IndexedTimestamp = a.IndexedTimestamp
@@ -73,33 +106,63 @@ func (a *Gorm) PopulateFromArticle(article *carticle.Article) error {
 		}
 		a.BlockData = postgres.Jsonb{RawMessage: blockJSON}
 	}
+	a.setBlockDataColumns(&article.BlockData)
 
-	a.NewsroomAddress = article.NewsroomAddress
+	a.NewsroomAddress = NormalizeNewsroomAddress(article.NewsroomAddress)
 	a.IndexedTimestamp = article.IndexedTimestamp
 	a.RawJSON = postgres.Jsonb{RawMessage: article.RawJSON}
+	a.RawJSONText = string(article.RawJSON)
 	a.ID = article.ID
 
 	return nil
 }
 
+// setBlockDataColumns copies the queryable fields of the receipt onto the block data columns
+func (a *Gorm) setBlockDataColumns(receipt *ethTypes.Receipt) {
+	a.TxHash = ""
+	a.BlockNumber = 0
+	a.BlockHash = ""
+	a.TxStatus = 0
+
+	if receipt.TxHash == (ethCommon.Hash{}) {
+		return
+	}
+
+	a.TxHash = receipt.TxHash.Hex()
+	if receipt.BlockNumber != nil {
+		a.BlockNumber = receipt.BlockNumber.Uint64()
+	}
+	if receipt.BlockHash != (ethCommon.Hash{}) {
+		a.BlockHash = receipt.BlockHash.Hex()
+	}
+	a.TxStatus = receipt.Status
+}
+
 // GormPGPersister is a persister that uses gorm and postgres
 type GormPGPersister struct {
 	DB *gorm.DB
+	// RequireContentHashMatch fails creates and updates of articles whose raw json
+	// does not match their RevisionContentHash
+	RequireContentHashMatch bool
+}
+
+// NewGormPGPersister return a new persister. The connection pool uses the defaults of
+// gormutils.NewOptions unless set by opts.
+func NewGormPGPersister(host string, port int, user string, password string, dbname string,
+	opts ...gormutils.Option) (*GormPGPersister, error) {
+	return NewGormPGPersisterWithConfig(gormutils.NewConfig(host, port, user, password, dbname), opts...)
 }
 
-// NewGormPGPersister return a new persister
-func NewGormPGPersister(host string, port int, user string, password string, dbname string) (*GormPGPersister, error) {
+// NewGormPGPersisterWithConfig takes the connection config of the db and returns a new persister.
+// Use it to connect with TLS or the other options of gormutils.Config. The connection pool
+// uses the defaults of gormutils.NewOptions unless set by opts.
+func NewGormPGPersisterWithConfig(cfg *gormutils.Config, opts ...gormutils.Option) (*GormPGPersister, error) {
 	articleGormPGPersister := &GormPGPersister{}
-	psqlInfo := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable", host, port, user, password, dbname)
-	db, err := gorm.Open("postgres", psqlInfo)
+	db, err := gormutils.NewGormPGConnectionWithConfig(cfg, opts...)
 	if err != nil {
-		return articleGormPGPersister, errors.Wrap(err, "error connecting to gorm")
+		return articleGormPGPersister, err
 	}
 	articleGormPGPersister.DB = db
-
-	articleGormPGPersister.DB.DB().SetMaxOpenConns(maxOpenConns)
-	articleGormPGPersister.DB.DB().SetMaxIdleConns(maxIdleConns)
-	articleGormPGPersister.DB.DB().SetConnMaxLifetime(connMaxLifetime)
 	return articleGormPGPersister, nil
 }
 
@@ -111,43 +174,173 @@ func NewGormPGPersisterWithDB(db *gorm.DB) (*GormPGPersister, error) {
 	return newsroomGormPGPersister, nil
 }
 
-// ArticleRawJSONIndex adds an GIN index to the article raw_json field.  Adding GIN indices
-// is not supported by gorm, so need to add it on table setup.
+// ArticleRawJSONIndex adds the GIN index on the article raw_json field by applying the
+// models migrations.
+//
+// Deprecated: use migrations.Up, which creates the index.
 func (p *GormPGPersister) ArticleRawJSONIndex() error {
+	return migrations.Up(p.DB)
+}
+
+// ArticleMetadataIndex adds the expression indices on the CanonicalURL and
+// RevisionContentHash fields of article_metadata by applying the models migrations.
+//
+// Deprecated: use migrations.Up, which creates the indices.
+func (p *GormPGPersister) ArticleMetadataIndex() error {
+	return migrations.Up(p.DB)
+}
+
+// ArticleCanonicalURLUniqueIndex adds the unique index on newsroom address and canonical URL
+// that UpsertArticle relies on to detect existing articles. Soft deleted articles and articles
+// without a canonical URL are excluded from the index. The index is not part of the schema
+// migrations, so only dbs that use UpsertArticle need to add it.
+//
+// Returns ErrDuplicateArticles if a newsroom already has more than one article with the same
+// canonical URL. SoftDeleteDuplicateArticles can be used to resolve them first. Once the index
+// exists, CreateArticle, CreateArticles and newsroom AddArticle return an error for a second
+// article with the same newsroom and canonical URL, use UpsertArticle to save new revisions instead.
+func (p *GormPGPersister) ArticleCanonicalURLUniqueIndex() error {
 	tblName := Gorm{}.TableName()
-	indexName := "idx_" + tblName + "_raw_json"
+	indexName := "idx_" + tblName + "_newsroom_address_canonical_url"
 	indexQuery := fmt.Sprintf(
-		"CREATE INDEX IF NOT EXISTS %s ON %s USING gin (raw_json)",
+		"CREATE UNIQUE INDEX IF NOT EXISTS %s ON %s (newsroom_address, (article_metadata->>'CanonicalURL')) WHERE %s",
 		indexName,
 		tblName,
+		canonicalURLIndexPredicate,
 	)
-	return p.DB.Exec(indexQuery).Error
+	return withTransaction(p.DB, func(tx *gorm.DB) error {
+		ids, err := duplicateArticleIDs(tx)
+		if err != nil {
+			return err
+		}
+		if len(ids) > 0 {
+			return errors.Wrapf(ErrDuplicateArticles, "%v duplicate articles", len(ids))
+		}
+		return tx.Exec(indexQuery).Error
+	})
+}
+
+// SoftDeleteDuplicateArticles soft deletes the articles that share a newsroom and canonical
+// URL with a more recently created article, keeping the article ArticleByCanonicalURL would
+// return. Returns the IDs of the soft deleted articles.
+func (p *GormPGPersister) SoftDeleteDuplicateArticles() ([]uint, error) {
+	var ids []uint
+	err := withTransaction(p.DB, func(tx *gorm.DB) error {
+		var err error
+		ids, err = duplicateArticleIDs(tx)
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		return tx.Where("id IN (?)", ids).Delete(&Gorm{}).Error
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error soft deleting duplicate articles")
+	}
+	return ids, nil
+}
+
+// duplicateArticleIDs returns the IDs of the articles that would conflict on the index from
+// ArticleCanonicalURLUniqueIndex, except for the most recent article of each newsroom and
+// canonical URL
+func duplicateArticleIDs(tx *gorm.DB) ([]uint, error) {
+	tblName := Gorm{}.TableName()
+	query := fmt.Sprintf(
+		`SELECT id FROM (
+			SELECT id, row_number() OVER (
+				PARTITION BY newsroom_address, article_metadata->>'CanonicalURL' ORDER BY id DESC
+			) AS duplicate
+			FROM %s WHERE %s
+		) AS duplicates WHERE duplicate > 1 ORDER BY id`,
+		tblName,
+		canonicalURLIndexPredicate,
+	)
+	dbRows, err := tx.Raw(query).Rows()
+	if err != nil {
+		return nil, errors.Wrap(err, "error finding duplicate articles")
+	}
+	defer dbRows.Close() // nolint: errcheck
+
+	ids := []uint{}
+	for dbRows.Next() {
+		var id uint
+		if err := dbRows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, dbRows.Err()
+}
+
+// ArticleListingIndex adds the index on (indexed_timestamp, id) used for the keyset
+// pagination in ListArticles by applying the models migrations.
+//
+// Deprecated: use migrations.Up, which creates the index.
+func (p *GormPGPersister) ArticleListingIndex() error {
+	return migrations.Up(p.DB)
 }
 
 // ArticleByID finds an article by its ID
-func (p *GormPGPersister) ArticleByID(articleID uint) (*carticle.Article, error) {
+func (p *GormPGPersister) ArticleByID(articleID uint, opts ...LookupOption) (*carticle.Article, error) {
 	articleGorm := &Gorm{}
-	if err := p.DB.First(articleGorm, articleID).Error; err != nil {
+	if err := lookupDB(p.DB, opts).First(articleGorm, articleID).Error; err != nil {
 		return nil, err
 	}
 
 	return articleGorm.ConvertToArticle()
 }
 
-// CreateArticle saves an article to the db
-func (p *GormPGPersister) CreateArticle(article *carticle.Article) error {
-	metaJSON, err := json.Marshal(article.ArticleMetadata)
+// ArticleByCanonicalURL finds the most recently created article with the given canonical URL.
+// Returns ErrArticleNotFound if there is no match.
+func (p *GormPGPersister) ArticleByCanonicalURL(canonicalURL string, opts ...LookupOption) (*carticle.Article, error) {
+	return p.articleByMetadataField("CanonicalURL", canonicalURL, opts)
+}
+
+// ArticleByRevisionContentHash finds the most recently created article with the given
+// revision content hash. Returns ErrArticleNotFound if there is no match.
+func (p *GormPGPersister) ArticleByRevisionContentHash(contentHash string, opts ...LookupOption) (*carticle.Article, error) {
+	return p.articleByMetadataField("RevisionContentHash", contentHash, opts)
+}
+
+func (p *GormPGPersister) articleByMetadataField(field string, value string,
+	opts []LookupOption) (*carticle.Article, error) {
+	articleGorm := &Gorm{}
+	err := lookupDB(p.DB, opts).
+		Where(fmt.Sprintf("article_metadata->>'%s' = ?", field), value).
+		Order("id DESC").
+		First(articleGorm).Error
 	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrArticleNotFound
+		}
+		return nil, err
+	}
+
+	return articleGorm.ConvertToArticle()
+}
+
+// CreateArticle saves an article to the db. If the opt in index from
+// ArticleCanonicalURLUniqueIndex exists, returns an error if the newsroom already has an
+// article with the same canonical URL.
+func (p *GormPGPersister) CreateArticle(article *carticle.Article) error {
+	if err := p.verifyContentHash(article); err != nil {
 		return err
 	}
 
-	articleGorm := Gorm{
-		NewsroomAddress: article.NewsroomAddress,
-		ArticleMetadata: postgres.Jsonb{RawMessage: metaJSON},
-		RawJSON:         postgres.Jsonb{RawMessage: article.RawJSON},
+	articleGorm, err := newArticleGorm(article)
+	if err != nil {
+		return err
 	}
 
-	if err := p.DB.Create(&articleGorm).Error; err != nil {
+	err = withTransaction(p.DB, func(tx *gorm.DB) error {
+		if err := tx.Create(articleGorm).Error; err != nil {
+			return err
+		}
+		if err := saveArticleAssociations(tx, articleGorm.ID, article); err != nil {
+			return err
+		}
+		return saveRevision(tx, articleGorm)
+	})
+	if err != nil {
 		return err
 	}
 
@@ -155,17 +348,129 @@ func (p *GormPGPersister) CreateArticle(article *carticle.Article) error {
 	return nil
 }
 
-// UpdateArticle saves updates to an article stuct
+// newArticleGorm returns the gorm struct for a new article. Block data is only
+// saved by updates.
+func newArticleGorm(article *carticle.Article) (*Gorm, error) {
+	metaJSON, err := json.Marshal(article.ArticleMetadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Gorm{
+		NewsroomAddress:  NormalizeNewsroomAddress(article.NewsroomAddress),
+		ArticleMetadata:  postgres.Jsonb{RawMessage: metaJSON},
+		IndexedTimestamp: article.IndexedTimestamp,
+		RawJSON:          postgres.Jsonb{RawMessage: article.RawJSON},
+		RawJSONText:      string(article.RawJSON),
+		AnchorState:      AnchorStatePending,
+	}, nil
+}
+
+// UpdateArticle saves updates to an article stuct. Returns ErrArticleNotFound if the
+// article is soft deleted, it has to be restored first.
 func (p *GormPGPersister) UpdateArticle(article *carticle.Article) error {
+	if err := p.verifyContentHash(article); err != nil {
+		return err
+	}
+
 	articleGorm := Gorm{}
 
 	if err := articleGorm.PopulateFromArticle(article); err != nil {
 		return err
 	}
 
-	if err := p.DB.Save(&articleGorm).Error; err != nil {
+	return withTransaction(p.DB, func(tx *gorm.DB) error {
+		if err := checkNotDeleted(tx, articleGorm.ID); err != nil {
+			return err
+		}
+		// The anchor state only changes through transitions
+		if err := tx.Omit("anchor_state").Save(&articleGorm).Error; err != nil {
+			return err
+		}
+		if articleGorm.TxHash != "" {
+			if err := confirmAnchorState(tx, articleGorm.ID); err != nil {
+				return err
+			}
+		}
+		if err := saveArticleAssociations(tx, articleGorm.ID, article); err != nil {
+			return err
+		}
+		return saveRevision(tx, &articleGorm)
+	})
+}
+
+// checkNotDeleted returns ErrArticleNotFound if the article is soft deleted. Saving it
+// would otherwise update no rows through the default scope and try to insert it again.
+func checkNotDeleted(tx *gorm.DB, articleID uint) error {
+	if articleID == 0 {
+		return nil
+	}
+	count := 0
+	err := tx.Unscoped().Model(&Gorm{}).
+		Where("id = ? AND deleted_at IS NOT NULL", articleID).
+		Count(&count).Error
+	if err != nil {
 		return err
 	}
-
+	if count > 0 {
+		return ErrArticleNotFound
+	}
 	return nil
 }
+
+// saveArticleAssociations brings the rows linked to the article in line with its metadata
+func saveArticleAssociations(tx *gorm.DB, articleID uint, article *carticle.Article) error {
+	if err := syncArticleTags(tx, articleID, &article.ArticleMetadata); err != nil {
+		return err
+	}
+	if err := syncArticleContributors(tx, articleID, article); err != nil {
+		return err
+	}
+	return syncArticleImages(tx, articleID, &article.ArticleMetadata)
+}
+
+// BackfillArticleAssociations links all existing articles, including deleted ones, to
+// their tags, contributors and images. Articles are linked when they are created or updated,
+// this is only needed for articles saved before the tables existed.
+func (p *GormPGPersister) BackfillArticleAssociations() error {
+	return p.backfillArticles(func(tx *gorm.DB, article *carticle.Article) error {
+		return saveArticleAssociations(tx, article.ID, article)
+	})
+}
+
+// BackfillArticleTags links all existing articles, including deleted ones, to their tags.
+// Articles are linked when they are created or updated, this is only needed for articles
+// saved before the tags tables existed. Use BackfillArticleAssociations to also link the
+// contributors and images.
+func (p *GormPGPersister) BackfillArticleTags() error {
+	return p.backfillArticles(func(tx *gorm.DB, article *carticle.Article) error {
+		return syncArticleTags(tx, article.ID, &article.ArticleMetadata)
+	})
+}
+
+// backfillArticles calls fn for every article, including deleted ones, each in a transaction
+func (p *GormPGPersister) backfillArticles(fn func(tx *gorm.DB, article *carticle.Article) error) error {
+	filter := &ArticleFilter{IncludeDeleted: true}
+	page := &PageRequest{Limit: listcursor.MaxLimit}
+	for {
+		listing, err := p.ListArticles(filter, page)
+		if err != nil {
+			return err
+		}
+
+		for i := range listing.Articles {
+			a := &listing.Articles[i]
+			err := withTransaction(p.DB, func(tx *gorm.DB) error {
+				return fn(tx, a)
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if listing.NextCursor == "" {
+			return nil
+		}
+		page.Cursor = listing.NextCursor
+	}
+}
@@ -16,11 +16,15 @@ import (
 func testFunc(persister article.Persister) {
 }
 
+func testContextFunc(persister article.ContextPersister) {
+}
+
 func TestGormInterface(t *testing.T) {
 	// Ensure the GORM persister implements the Persister interface
 	creds := testutils.GetTestDBConnection()
 	pg, _ := article.NewGormPGPersister(creds.Host, creds.Port, creds.User, creds.Password, creds.Dbname)
 	testFunc(pg)
+	testContextFunc(pg)
 }
 
 func TestCreateArticle(t *testing.T) {
@@ -157,3 +161,201 @@ func TestUpdateArticle(t *testing.T) {
 		t.Errorf("should have saved the new tx receipt")
 	}
 }
+
+func TestListArticles(t *testing.T) {
+	creds := testutils.GetTestDBConnection()
+	pg, err := article.NewGormPGPersister(creds.Host, creds.Port, creds.User, creds.Password, creds.Dbname)
+
+	if err != nil {
+		fmt.Println(err)
+		t.Errorf("threw an error making the persister")
+	}
+
+	testutils.MigrateModels(pg.DB) // nolint: errcheck
+
+	defer pg.DB.Close()
+
+	cleaner := testutils.DeleteCreatedEntities(pg.DB)
+	defer cleaner()
+
+	if err := pg.ArticleListingIndex(); err != nil {
+		t.Errorf("should not have returned error adding index")
+	}
+
+	now := time.Now()
+	newsroomAddr := "0x8c722B8AC728aDd7780a66017e8daDBa530EE261"
+
+	for i := 0; i < 5; i++ {
+		narticle := &carticle.Article{
+			ArticleMetadata: carticle.Metadata{
+				Title:               fmt.Sprintf("article %v", i),
+				CanonicalURL:        fmt.Sprintf("https://newstuff.bz/article%v", i),
+				Tags:                []string{"news"},
+				OriginalPublishDate: now.Add(time.Duration(i) * time.Hour),
+			},
+			NewsroomAddress:  newsroomAddr,
+			IndexedTimestamp: now.Add(time.Duration(i) * time.Second),
+		}
+		if err := pg.CreateArticle(narticle); err != nil {
+			t.Errorf("should have created article: err: %v", err)
+		}
+	}
+
+	otherArticle := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Title:        "other newsroom",
+			CanonicalURL: "https://otherstuff.bz/article",
+			Tags:         []string{"sports"},
+		},
+		NewsroomAddress:  "0x9c722B8AF728aDd7780a66017e8daDBa530EE261",
+		IndexedTimestamp: now,
+	}
+	if err := pg.CreateArticle(otherArticle); err != nil {
+		t.Errorf("should have created article: err: %v", err)
+	}
+
+	filter := &article.ArticleFilter{NewsroomAddress: newsroomAddr}
+	listing, err := pg.ListArticles(filter, &article.PageRequest{Limit: 2})
+	if err != nil {
+		t.Fatalf("should have listed articles: err: %v", err)
+	}
+	if len(listing.Articles) != 2 {
+		t.Errorf("should have returned 2 articles: len: %v", len(listing.Articles))
+	}
+	if listing.Articles[0].ArticleMetadata.Title != "article 4" {
+		t.Errorf("should have returned the most recently indexed article first")
+	}
+	if listing.NextCursor == "" {
+		t.Errorf("should have returned a cursor for the next page")
+	}
+
+	titles := []string{}
+	page := &article.PageRequest{Limit: 2}
+	for {
+		listing, err = pg.ListArticles(filter, page)
+		if err != nil {
+			t.Fatalf("should have listed articles: err: %v", err)
+		}
+		for _, a := range listing.Articles {
+			titles = append(titles, a.ArticleMetadata.Title)
+		}
+		if listing.NextCursor == "" {
+			break
+		}
+		page.Cursor = listing.NextCursor
+	}
+	if len(titles) != 5 {
+		t.Errorf("should have paged through all 5 articles: len: %v", len(titles))
+	}
+
+	listing, err = pg.ListArticles(&article.ArticleFilter{Tag: "sports"}, nil)
+	if err != nil {
+		t.Errorf("should have listed articles: err: %v", err)
+	}
+	if len(listing.Articles) != 1 {
+		t.Errorf("should have returned only the sports article: len: %v", len(listing.Articles))
+	}
+
+	listing, err = pg.ListArticles(&article.ArticleFilter{
+		NewsroomAddress: newsroomAddr,
+		IndexedAfter:    now.Add(2 * time.Second),
+		PublishedBefore: now.Add(4 * time.Hour),
+	}, nil)
+	if err != nil {
+		t.Errorf("should have listed articles: err: %v", err)
+	}
+	if len(listing.Articles) != 2 {
+		t.Errorf("should have returned 2 articles in the ranges: len: %v", len(listing.Articles))
+	}
+
+	hasBlockData := true
+	listing, err = pg.ListArticles(&article.ArticleFilter{HasBlockData: &hasBlockData}, nil)
+	if err != nil {
+		t.Errorf("should have listed articles: err: %v", err)
+	}
+	if len(listing.Articles) != 0 {
+		t.Errorf("should not have returned articles without block data")
+	}
+
+	_, err = pg.ListArticles(nil, &article.PageRequest{Cursor: "notacursor"})
+	if err != article.ErrInvalidCursor {
+		t.Errorf("should have returned invalid cursor error: err: %v", err)
+	}
+}
+
+func TestArticleByCanonicalURLAndContentHash(t *testing.T) {
+	creds := testutils.GetTestDBConnection()
+	pg, err := article.NewGormPGPersister(creds.Host, creds.Port, creds.User, creds.Password, creds.Dbname)
+
+	if err != nil {
+		fmt.Println(err)
+		t.Errorf("threw an error making the persister")
+	}
+
+	testutils.MigrateModels(pg.DB) // nolint: errcheck
+
+	defer pg.DB.Close()
+
+	cleaner := testutils.DeleteCreatedEntities(pg.DB)
+	defer cleaner()
+
+	if err := pg.ArticleMetadataIndex(); err != nil {
+		t.Errorf("should not have returned error adding index: err: %v", err)
+	}
+
+	narticle := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Title:               "new stufff",
+			CanonicalURL:        "https://newstuff.bz/lookuparticle",
+			RevisionContentHash: "0xabc123",
+		},
+		NewsroomAddress: "0x8c722B8AC728aDd7780a66017e8daDBa530EE261",
+	}
+
+	pg.CreateArticle(narticle) // nolint: errcheck
+
+	foundarticle, err := pg.ArticleByCanonicalURL("https://newstuff.bz/lookuparticle")
+	if err != nil {
+		t.Errorf("threw an error looking up the article by url: err: %v", err)
+	}
+	if foundarticle.ID != narticle.ID {
+		t.Errorf("found the wrong article by url")
+	}
+
+	foundarticle, err = pg.ArticleByRevisionContentHash("0xabc123")
+	if err != nil {
+		t.Errorf("threw an error looking up the article by hash: err: %v", err)
+	}
+	if foundarticle.ID != narticle.ID {
+		t.Errorf("found the wrong article by hash")
+	}
+
+	_, err = pg.ArticleByCanonicalURL("https://newstuff.bz/notanarticle")
+	if err != article.ErrArticleNotFound {
+		t.Errorf("should have returned ErrArticleNotFound: err: %v", err)
+	}
+
+	_, err = pg.ArticleByRevisionContentHash("0xnotahash")
+	if err != article.ErrArticleNotFound {
+		t.Errorf("should have returned ErrArticleNotFound: err: %v", err)
+	}
+}
+
+func TestGormPGPersisterSuite(t *testing.T) {
+	testutils.RunArticlePersisterSuite(t, func(t *testing.T) (article.Persister, func()) {
+		creds := testutils.GetTestDBConnection()
+		pg, err := article.NewGormPGPersister(creds.Host, creds.Port, creds.User, creds.Password, creds.Dbname)
+		if err != nil {
+			t.Fatalf("should have connected to the db: err: %v", err)
+		}
+		testutils.MigrateModels(pg.DB) // nolint: errcheck
+
+		cleaner := testutils.DeleteCreatedEntities(pg.DB)
+		return pg, func() {
+			cleaner()
+			// Tags are upserted with raw sql, so they are not cleaned up with the articles
+			pg.DB.Exec("DELETE FROM tags WHERE id NOT IN (SELECT tag_id FROM article_tags)") // nolint: errcheck
+			pg.DB.Close()                                                                    // nolint: errcheck
+		}
+	})
+}
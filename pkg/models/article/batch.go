@@ -0,0 +1,292 @@
+package article
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	gormutils "github.com/joincivil/go-common-priv/pkg/utils/gorm"
+	carticle "github.com/joincivil/go-common/pkg/article"
+	"github.com/pkg/errors"
+)
+
+const (
+	// batchInsertSize is the number of articles per INSERT, well below the
+	// postgres limit of 65535 bind parameters
+	batchInsertSize = 1000
+)
+
+// batchInsertColumns are the columns set by CreateArticles, the same as CreateArticle
+var batchInsertColumns = []string{
+	"created_at",
+	"updated_at",
+	"newsroom_address",
+	"article_metadata",
+	"raw_json",
+	"raw_json_text",
+	"block_data",
+	"indexed_timestamp",
+	"tx_hash",
+	"block_number",
+	"block_hash",
+	"tx_status",
+	"anchor_state",
+}
+
+// BatchOptions are the options for CreateArticles
+type BatchOptions struct {
+	// ContinueOnError saves the rest of the batch when articles fail, and returns
+	// BatchErrors for the articles that failed. Otherwise the whole batch is rolled back.
+	ContinueOnError bool
+}
+
+// BatchError is the error for an article in a batch
+type BatchError struct {
+	// Index is the index of the article in the batch
+	Index int
+	Err   error
+}
+
+// Error implements error
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("article %d: %v", e.Index, e.Err)
+}
+
+// Cause returns the error of the article, for errors.Cause
+func (e *BatchError) Cause() error {
+	return e.Err
+}
+
+// BatchErrors are the errors for the articles that were not saved in a batch
+type BatchErrors []*BatchError
+
+// Error implements error
+func (e BatchErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, batchErr := range e {
+		msgs[i] = batchErr.Error()
+	}
+	return fmt.Sprintf("%d articles failed: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// batchRow is an article being saved by CreateArticles
+type batchRow struct {
+	index       int
+	articleGorm *Gorm
+	failed      bool
+}
+
+// CreateArticles saves a batch of articles to the db in one transaction, using multi-row
+// inserts, and sets the IDs of the saved articles. By default the batch is rolled back if
+// any article fails. With ContinueOnError, the other articles are still saved and the
+// failed articles are returned as BatchErrors, with their IDs left at zero.
+func (p *GormPGPersister) CreateArticles(articles []*carticle.Article, opts *BatchOptions) error {
+	if opts == nil {
+		opts = &BatchOptions{}
+	}
+
+	batchErrs := BatchErrors{}
+	fail := func(row *batchRow, err error) error {
+		row.failed = true
+		batchErr := &BatchError{Index: row.index, Err: err}
+		if !opts.ContinueOnError {
+			return batchErr
+		}
+		batchErrs = append(batchErrs, batchErr)
+		return nil
+	}
+
+	rows := make([]*batchRow, 0, len(articles))
+	for i, article := range articles {
+		row := &batchRow{index: i}
+		err := p.verifyContentHash(article)
+		if err == nil {
+			row.articleGorm, err = newArticleGorm(article)
+		}
+		if err != nil {
+			if err := fail(row, err); err != nil {
+				return err
+			}
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	err := withTransaction(p.DB, func(tx *gorm.DB) error {
+		for start := 0; start < len(rows); start += batchInsertSize {
+			end := start + batchInsertSize
+			if end > len(rows) {
+				end = len(rows)
+			}
+			if err := insertArticleRows(tx, rows[start:end], opts, fail); err != nil {
+				return err
+			}
+		}
+
+		for _, row := range rows {
+			if row.failed {
+				continue
+			}
+			if err := saveBatchRowData(tx, articles[row.index], row, opts, fail); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if !row.failed {
+			articles[row.index].ID = row.articleGorm.ID
+		}
+	}
+
+	if len(batchErrs) > 0 {
+		return batchErrs
+	}
+	return nil
+}
+
+// insertArticleRows inserts the rows with one INSERT. With ContinueOnError, if the
+// INSERT fails, the rows are inserted one at a time to find the failing rows.
+func insertArticleRows(tx *gorm.DB, rows []*batchRow, opts *BatchOptions,
+	fail func(*batchRow, error) error) error {
+	if !opts.ContinueOnError {
+		return errors.Wrap(execArticleInsert(tx, rows), "error inserting articles")
+	}
+
+	err := gormutils.WithTx(tx, func(sp *gorm.DB) error {
+		return execArticleInsert(sp, rows)
+	})
+	if err == nil {
+		return nil
+	}
+
+	for _, row := range rows {
+		err := gormutils.WithTx(tx, func(sp *gorm.DB) error {
+			return execArticleInsert(sp, []*batchRow{row})
+		})
+		if err != nil {
+			if err := fail(row, err); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// execArticleInsert inserts the rows and sets their IDs. Postgres does not guarantee the
+// order of the ids returned by a multi-row insert, so the ids are taken from the sequence
+// first and inserted with the rows.
+func execArticleInsert(tx *gorm.DB, rows []*batchRow) error {
+	ids, err := nextArticleIDs(tx, len(rows))
+	if err != nil {
+		return err
+	}
+
+	placeholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(batchInsertColumns)+1), ", ") + ")"
+	values := make([]string, len(rows))
+	args := make([]interface{}, 0, len(rows)*(len(batchInsertColumns)+1))
+
+	now := gorm.NowFunc()
+	for i, row := range rows {
+		a := row.articleGorm
+		// The insert does not run the gorm callbacks, so the anchor state is set here as
+		// BeforeCreate sets it for CreateArticle
+		if err := a.BeforeCreate(); err != nil {
+			return err
+		}
+		values[i] = placeholders
+		args = append(
+			args,
+			ids[i],
+			now,
+			now,
+			a.NewsroomAddress,
+			a.ArticleMetadata,
+			a.RawJSON,
+			a.RawJSONText,
+			a.BlockData,
+			a.IndexedTimestamp,
+			a.TxHash,
+			a.BlockNumber,
+			a.BlockHash,
+			a.TxStatus,
+			a.AnchorState,
+		)
+	}
+
+	query := fmt.Sprintf(
+		"INSERT INTO %s (id, %s) VALUES %s",
+		Gorm{}.TableName(),
+		strings.Join(batchInsertColumns, ", "),
+		strings.Join(values, ", "),
+	)
+	if err := tx.Exec(query, args...).Error; err != nil {
+		return err
+	}
+
+	for i, row := range rows {
+		row.articleGorm.ID = ids[i]
+	}
+	return nil
+}
+
+// nextArticleIDs takes n ids from the sequence of the article ids
+func nextArticleIDs(tx *gorm.DB, n int) ([]uint, error) {
+	dbRows, err := tx.Raw(
+		"SELECT nextval(pg_get_serial_sequence(?, 'id')) FROM generate_series(1, ?)",
+		Gorm{}.TableName(),
+		n,
+	).Rows()
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting article ids")
+	}
+	defer dbRows.Close() // nolint: errcheck
+
+	ids := make([]uint, 0, n)
+	for dbRows.Next() {
+		var id uint
+		if err := dbRows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := dbRows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) != n {
+		return nil, errors.New("fewer ids returned than articles inserted")
+	}
+	return ids, nil
+}
+
+// saveBatchRowData saves the associations and revision of an inserted article. With
+// ContinueOnError, the article is deleted again if they cannot be saved.
+func saveBatchRowData(tx *gorm.DB, article *carticle.Article, row *batchRow, opts *BatchOptions,
+	fail func(*batchRow, error) error) error {
+	save := func(tx *gorm.DB) error {
+		if err := saveArticleAssociations(tx, row.articleGorm.ID, article); err != nil {
+			return err
+		}
+		return saveRevision(tx, row.articleGorm)
+	}
+
+	if !opts.ContinueOnError {
+		if err := save(tx); err != nil {
+			return fail(row, err)
+		}
+		return nil
+	}
+
+	if err := gormutils.WithTx(tx, save); err != nil {
+		deleteErr := tx.Unscoped().Where("id = ?", row.articleGorm.ID).Delete(&Gorm{}).Error
+		if deleteErr != nil {
+			return errors.Wrap(deleteErr, "error deleting failed article")
+		}
+		return fail(row, err)
+	}
+	return nil
+}
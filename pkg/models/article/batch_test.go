@@ -0,0 +1,150 @@
+package article_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/joincivil/go-common-priv/pkg/models/article"
+	"github.com/joincivil/go-common-priv/pkg/models/testutils"
+	carticle "github.com/joincivil/go-common/pkg/article"
+	"github.com/pkg/errors"
+)
+
+func TestCreateArticles(t *testing.T) {
+	creds := testutils.GetTestDBConnection()
+	pg, err := article.NewGormPGPersister(creds.Host, creds.Port, creds.User, creds.Password, creds.Dbname)
+
+	if err != nil {
+		fmt.Println(err)
+		t.Errorf("threw an error making the persister")
+	}
+
+	testutils.MigrateModels(pg.DB) // nolint: errcheck
+
+	defer pg.DB.Close()
+
+	// The unique index is opt in, so the batches are saved in a transaction with the index
+	// that is rolled back
+	tx := pg.DB.Begin()
+	defer tx.Rollback()
+	txPersister := uniqueIndexPersister(t, tx)
+
+	newsroomAddr := "0x7c722B8AC728aDd7780a66017e8daDBa530EE261"
+	newBatch := func() []*carticle.Article {
+		batch := []*carticle.Article{}
+		for i := 0; i < 3; i++ {
+			batch = append(batch, &carticle.Article{
+				ArticleMetadata: carticle.Metadata{
+					Title:        fmt.Sprintf("batch article %d", i),
+					CanonicalURL: fmt.Sprintf("https://newstuff.bz/batcharticle%d", i),
+					Tags:         []string{"batch"},
+				},
+				NewsroomAddress: newsroomAddr,
+				RawJSON:         []byte(fmt.Sprintf(`{"title": "batch article %d"}`, i)),
+			})
+		}
+		// Same canonical URL as the first article in the batch
+		batch[2].ArticleMetadata.CanonicalURL = batch[0].ArticleMetadata.CanonicalURL
+		return batch
+	}
+
+	batch := newBatch()
+	err = txPersister.CreateArticles(batch, nil)
+	if err == nil {
+		t.Fatalf("should have failed the batch with a duplicate canonical url")
+	}
+	for _, a := range batch {
+		if a.ID != 0 {
+			t.Errorf("should not have set ids on a failed batch")
+		}
+	}
+
+	batch = newBatch()
+	err = txPersister.CreateArticles(batch, &article.BatchOptions{ContinueOnError: true})
+
+	batchErrs, ok := err.(article.BatchErrors)
+	if !ok || len(batchErrs) != 1 || batchErrs[0].Index != 2 {
+		t.Fatalf("should have returned the error for the duplicate article: err: %v", err)
+	}
+	if batch[0].ID == 0 || batch[1].ID == 0 || batch[2].ID != 0 {
+		t.Errorf("should have only set ids of the saved articles")
+	}
+
+	for i, a := range batch[:2] {
+		saved, err := txPersister.ArticleByID(a.ID)
+		if err != nil {
+			t.Errorf("should have found the saved article: err: %v", err)
+		} else if saved.ArticleMetadata.Title != fmt.Sprintf("batch article %d", i) {
+			t.Errorf("should have set the id of the article it was saved as: %v", saved.ArticleMetadata.Title)
+		}
+	}
+
+	listing, err := txPersister.ArticlesByTag("batch", &article.ArticleFilter{NewsroomAddress: newsroomAddr}, nil)
+	if err != nil {
+		t.Errorf("should not have returned error: err: %v", err)
+	}
+	if len(listing.Articles) != 2 {
+		t.Errorf("should have linked the tags of the saved articles: %v", len(listing.Articles))
+	}
+
+	txPersister.RequireContentHashMatch = true
+	tampered := []*carticle.Article{{
+		ArticleMetadata: carticle.Metadata{
+			CanonicalURL:        "https://newstuff.bz/batchtampered",
+			RevisionContentHash: "0x1234",
+		},
+		NewsroomAddress: newsroomAddr,
+		RawJSON:         []byte(`{"title": "tampered"}`),
+	}}
+	err = txPersister.CreateArticles(tampered, nil)
+	if errors.Cause(err) != article.ErrContentHashMismatch {
+		t.Errorf("should have returned the content hash mismatch: err: %v", err)
+	}
+}
+
+func TestCreateArticlesAnchorState(t *testing.T) {
+	creds := testutils.GetTestDBConnection()
+	pg, err := article.NewGormPGPersister(creds.Host, creds.Port, creds.User, creds.Password, creds.Dbname)
+
+	if err != nil {
+		fmt.Println(err)
+		t.Errorf("threw an error making the persister")
+	}
+
+	testutils.MigrateModels(pg.DB) // nolint: errcheck
+
+	defer pg.DB.Close()
+
+	cleaner := testutils.DeleteCreatedEntities(pg.DB)
+	defer cleaner()
+
+	batch := []*carticle.Article{
+		{
+			ArticleMetadata: carticle.Metadata{CanonicalURL: "https://newstuff.bz/batchstate1"},
+			NewsroomAddress: "0x7c722B8AC728aDd7780a66017e8daDBa530EE261",
+		},
+		{
+			ArticleMetadata: carticle.Metadata{CanonicalURL: "https://newstuff.bz/batchstate2"},
+			NewsroomAddress: "0x7c722B8AC728aDd7780a66017e8daDBa530EE261",
+		},
+	}
+	err = pg.CreateArticles(batch, nil)
+
+	// The articles are inserted with raw sql, so they are not picked up by the cleanup hook
+	for _, a := range batch {
+		if a.ID != 0 {
+			testutils.RegisterCreatedEntities(pg.DB, &article.Gorm{Model: gorm.Model{ID: a.ID}})
+		}
+	}
+	if err != nil {
+		t.Fatalf("should have created the articles: err: %v", err)
+	}
+
+	for _, a := range batch {
+		state, err := pg.ArticleAnchorState(a.ID)
+		if err != nil || state != article.AnchorStatePending {
+			t.Errorf("should have created the article as pending: %v: err: %v", state, err)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package article
+
+import (
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/jinzhu/gorm"
+	carticle "github.com/joincivil/go-common/pkg/article"
+
+	"github.com/joincivil/go-common-priv/pkg/models/migrations"
+)
+
+// BackfillBlockDataColumns populates the tx_hash, block_number, block_hash and tx_status
+// columns from the block_data JSON by applying the models migrations.
+//
+// Deprecated: use migrations.Up, which backfills the columns when it adds them.
+func (p *GormPGPersister) BackfillBlockDataColumns() error {
+	return migrations.Up(p.DB)
+}
+
+// ArticleByTxHash finds the most recently created article anchored by the transaction
+// with the given hash. Returns ErrArticleNotFound if there is no match.
+func (p *GormPGPersister) ArticleByTxHash(txHash string, opts ...LookupOption) (*carticle.Article, error) {
+	articleGorm := &Gorm{}
+	err := lookupDB(p.DB, opts).
+		Where("tx_hash = ?", ethCommon.HexToHash(txHash).Hex()).
+		Order("id DESC").
+		First(articleGorm).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrArticleNotFound
+		}
+		return nil, err
+	}
+
+	return articleGorm.ConvertToArticle()
+}
+
+// ArticlesInBlockRange returns the articles anchored in blocks fromBlock to toBlock inclusive,
+// ordered by block number
+func (p *GormPGPersister) ArticlesInBlockRange(fromBlock uint64, toBlock uint64) ([]carticle.Article, error) {
+	articleGorms := []Gorm{}
+	err := p.DB.Where(
+		"tx_hash <> '' AND block_number >= ? AND block_number <= ?",
+		fromBlock,
+		toBlock,
+	).Order("block_number ASC, id ASC").Find(&articleGorms).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return convertArticleGorms(articleGorms)
+}
+
+func convertArticleGorms(articleGorms []Gorm) ([]carticle.Article, error) {
+	articles := make([]carticle.Article, len(articleGorms))
+	for i, a := range articleGorms {
+		convertedArticle, err := a.ConvertToArticle()
+		if err != nil {
+			return nil, err
+		}
+		articles[i] = *convertedArticle
+	}
+	return articles, nil
+}
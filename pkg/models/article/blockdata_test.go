@@ -0,0 +1,125 @@
+package article_test
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/joincivil/go-common-priv/pkg/models/article"
+	"github.com/joincivil/go-common-priv/pkg/models/migrations"
+	"github.com/joincivil/go-common-priv/pkg/models/testutils"
+	carticle "github.com/joincivil/go-common/pkg/article"
+)
+
+func TestBlockDataColumns(t *testing.T) {
+	creds := testutils.GetTestDBConnection()
+	pg, err := article.NewGormPGPersister(creds.Host, creds.Port, creds.User, creds.Password, creds.Dbname)
+
+	if err != nil {
+		fmt.Println(err)
+		t.Errorf("threw an error making the persister")
+	}
+
+	testutils.MigrateModels(pg.DB) // nolint: errcheck
+
+	defer pg.DB.Close()
+
+	cleaner := testutils.DeleteCreatedEntities(pg.DB)
+	defer cleaner()
+
+	narticle := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Title:        "anchored stuff",
+			CanonicalURL: "https://newstuff.bz/anchoredarticle",
+		},
+		NewsroomAddress: "0x8c722B8AC728aDd7780a66017e8daDBa530EE261",
+	}
+
+	if err := pg.CreateArticle(narticle); err != nil {
+		t.Fatalf("should have created article: err: %v", err)
+	}
+
+	receipt := testutils.MakeFakeReceipt()
+	receipt.BlockNumber = big.NewInt(1000)
+	receipt.BlockHash = ethCommon.HexToHash("0x1234")
+	receipt.Status = 1
+	narticle.BlockData = receipt
+
+	if err := pg.UpdateArticle(narticle); err != nil {
+		t.Errorf("should have updated article: err: %v", err)
+	}
+
+	articleGorm := &article.Gorm{}
+	if err := pg.DB.First(articleGorm, narticle.ID).Error; err != nil {
+		t.Errorf("should have found article: err: %v", err)
+	}
+	if articleGorm.TxHash != testutils.FakeTxHash {
+		t.Errorf("should have set the tx hash column: %v", articleGorm.TxHash)
+	}
+	if articleGorm.BlockNumber != 1000 {
+		t.Errorf("should have set the block number column: %v", articleGorm.BlockNumber)
+	}
+	if articleGorm.BlockHash != receipt.BlockHash.Hex() {
+		t.Errorf("should have set the block hash column: %v", articleGorm.BlockHash)
+	}
+	if articleGorm.TxStatus != 1 {
+		t.Errorf("should have set the tx status column: %v", articleGorm.TxStatus)
+	}
+
+	found, err := pg.ArticleByTxHash(testutils.FakeTxHash)
+	if err != nil {
+		t.Errorf("should have found article by tx hash: err: %v", err)
+	} else if found.ID != narticle.ID {
+		t.Errorf("found the wrong article by tx hash")
+	}
+
+	if _, err := pg.ArticleByTxHash("0xdeadbeef"); err != article.ErrArticleNotFound {
+		t.Errorf("should have returned ErrArticleNotFound: err: %v", err)
+	}
+
+	articles, err := pg.ArticlesInBlockRange(999, 1000)
+	if err != nil {
+		t.Errorf("should have retrieved articles in range: err: %v", err)
+	}
+	if len(articles) != 1 {
+		t.Errorf("should have retrieved the article in range: len: %v", len(articles))
+	}
+
+	articles, err = pg.ArticlesInBlockRange(1001, 2000)
+	if err != nil {
+		t.Errorf("should have retrieved articles in range: err: %v", err)
+	}
+	if len(articles) != 0 {
+		t.Errorf("should not have retrieved articles outside of the range: len: %v", len(articles))
+	}
+
+	// Clear the columns to test the backfill from the block data
+	err = pg.DB.Exec(
+		"UPDATE articles SET tx_hash = NULL, block_number = NULL, block_hash = NULL, tx_status = NULL WHERE id = ?",
+		narticle.ID,
+	).Error
+	if err != nil {
+		t.Errorf("should have cleared the columns: err: %v", err)
+	}
+
+	// The migration adding the columns backfills them
+	for _, migration := range migrations.All() {
+		if migration.Name != "add_articles_block_data_columns" {
+			continue
+		}
+		if err := migration.Up(pg.DB); err != nil {
+			t.Errorf("should have backfilled the columns: err: %v", err)
+		}
+	}
+
+	articleGorm = &article.Gorm{}
+	if err := pg.DB.First(articleGorm, narticle.ID).Error; err != nil {
+		t.Errorf("should have found article: err: %v", err)
+	}
+	if articleGorm.TxHash != testutils.FakeTxHash || articleGorm.BlockNumber != 1000 ||
+		articleGorm.BlockHash != receipt.BlockHash.Hex() || articleGorm.TxStatus != 1 {
+		t.Errorf("should have backfilled the columns: %v %v %v %v", articleGorm.TxHash,
+			articleGorm.BlockNumber, articleGorm.BlockHash, articleGorm.TxStatus)
+	}
+}
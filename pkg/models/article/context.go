@@ -0,0 +1,132 @@
+package article
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+
+	gormutils "github.com/joincivil/go-common-priv/pkg/utils/gorm"
+	carticle "github.com/joincivil/go-common/pkg/article"
+)
+
+// ContextPersister is the Persister interface with a context on every method. Cancelling
+// the context aborts the query in flight, and a passed deadline returns a
+// gormutils.TimeoutError.
+type ContextPersister interface {
+	ArticleByIDContext(ctx context.Context, articleID uint, opts ...LookupOption) (*carticle.Article, error)
+	ArticleByCanonicalURLContext(ctx context.Context, canonicalURL string,
+		opts ...LookupOption) (*carticle.Article, error)
+	ArticleByRevisionContentHashContext(ctx context.Context, contentHash string,
+		opts ...LookupOption) (*carticle.Article, error)
+	CreateArticleContext(ctx context.Context, article *carticle.Article) error
+	UpdateArticleContext(ctx context.Context, article *carticle.Article) error
+	ListArticlesContext(ctx context.Context, filter *ArticleFilter, page *PageRequest) (*ArticleListing, error)
+	IterateArticlesContext(ctx context.Context, filter *ArticleFilter, batchSize int) Iterator
+	DeleteArticleContext(ctx context.Context, articleID uint) error
+	RestoreArticleContext(ctx context.Context, articleID uint) error
+	PurgeArticleContext(ctx context.Context, articleID uint) error
+}
+
+// WithContext runs fn with a copy of the persister whose queries run in a transaction
+// bound to the context, for the methods that have no Context variant. The transaction is
+// committed if fn returns nil and rolled back otherwise. Errors are returned as is, use
+// gormutils.ContextError to map them.
+func (p *GormPGPersister) WithContext(ctx context.Context, fn func(p *GormPGPersister) error) error {
+	return gormutils.WithContext(ctx, p.DB, func(tx *gorm.DB) error {
+		ctxPersister := *p
+		ctxPersister.DB = tx
+		return fn(&ctxPersister)
+	})
+}
+
+// ArticleByIDContext is ArticleByID with a context
+func (p *GormPGPersister) ArticleByIDContext(ctx context.Context, articleID uint,
+	opts ...LookupOption) (*carticle.Article, error) {
+	var article *carticle.Article
+	err := p.WithContext(ctx, func(p *GormPGPersister) error {
+		var err error
+		article, err = p.ArticleByID(articleID, opts...)
+		return err
+	})
+	return article, gormutils.ContextError(ctx, err)
+}
+
+// ArticleByCanonicalURLContext is ArticleByCanonicalURL with a context
+func (p *GormPGPersister) ArticleByCanonicalURLContext(ctx context.Context, canonicalURL string,
+	opts ...LookupOption) (*carticle.Article, error) {
+	var article *carticle.Article
+	err := p.WithContext(ctx, func(p *GormPGPersister) error {
+		var err error
+		article, err = p.ArticleByCanonicalURL(canonicalURL, opts...)
+		return err
+	})
+	return article, gormutils.ContextError(ctx, err)
+}
+
+// ArticleByRevisionContentHashContext is ArticleByRevisionContentHash with a context
+func (p *GormPGPersister) ArticleByRevisionContentHashContext(ctx context.Context, contentHash string,
+	opts ...LookupOption) (*carticle.Article, error) {
+	var article *carticle.Article
+	err := p.WithContext(ctx, func(p *GormPGPersister) error {
+		var err error
+		article, err = p.ArticleByRevisionContentHash(contentHash, opts...)
+		return err
+	})
+	return article, gormutils.ContextError(ctx, err)
+}
+
+// CreateArticleContext is CreateArticle with a context
+func (p *GormPGPersister) CreateArticleContext(ctx context.Context, article *carticle.Article) error {
+	return gormutils.ContextError(ctx, p.WithContext(ctx, func(p *GormPGPersister) error {
+		return p.CreateArticle(article)
+	}))
+}
+
+// UpdateArticleContext is UpdateArticle with a context
+func (p *GormPGPersister) UpdateArticleContext(ctx context.Context, article *carticle.Article) error {
+	return gormutils.ContextError(ctx, p.WithContext(ctx, func(p *GormPGPersister) error {
+		return p.UpdateArticle(article)
+	}))
+}
+
+// ListArticlesContext is ListArticles with a context
+func (p *GormPGPersister) ListArticlesContext(ctx context.Context, filter *ArticleFilter,
+	page *PageRequest) (*ArticleListing, error) {
+	var listing *ArticleListing
+	err := p.WithContext(ctx, func(p *GormPGPersister) error {
+		var err error
+		listing, err = p.ListArticles(filter, page)
+		return err
+	})
+	return listing, gormutils.ContextError(ctx, err)
+}
+
+// IterateArticlesContext is IterateArticles with a context. The iteration stops with
+// the context error once the context is done.
+func (p *GormPGPersister) IterateArticlesContext(ctx context.Context, filter *ArticleFilter,
+	batchSize int) Iterator {
+	return NewListIterator(func(page *PageRequest) (*ArticleListing, error) {
+		return p.ListArticlesContext(ctx, filter, page)
+	}, batchSize)
+}
+
+// DeleteArticleContext is DeleteArticle with a context
+func (p *GormPGPersister) DeleteArticleContext(ctx context.Context, articleID uint) error {
+	return gormutils.ContextError(ctx, p.WithContext(ctx, func(p *GormPGPersister) error {
+		return p.DeleteArticle(articleID)
+	}))
+}
+
+// RestoreArticleContext is RestoreArticle with a context
+func (p *GormPGPersister) RestoreArticleContext(ctx context.Context, articleID uint) error {
+	return gormutils.ContextError(ctx, p.WithContext(ctx, func(p *GormPGPersister) error {
+		return p.RestoreArticle(articleID)
+	}))
+}
+
+// PurgeArticleContext is PurgeArticle with a context
+func (p *GormPGPersister) PurgeArticleContext(ctx context.Context, articleID uint) error {
+	return gormutils.ContextError(ctx, p.WithContext(ctx, func(p *GormPGPersister) error {
+		return p.PurgeArticle(articleID)
+	}))
+}
@@ -0,0 +1,73 @@
+package article_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/joincivil/go-common-priv/pkg/models/article"
+	"github.com/joincivil/go-common-priv/pkg/models/testutils"
+	gormutils "github.com/joincivil/go-common-priv/pkg/utils/gorm"
+	carticle "github.com/joincivil/go-common/pkg/article"
+)
+
+func TestArticleContextMethods(t *testing.T) {
+	creds := testutils.GetTestDBConnection()
+	pg, err := article.NewGormPGPersister(creds.Host, creds.Port, creds.User, creds.Password, creds.Dbname)
+
+	if err != nil {
+		fmt.Println(err)
+		t.Errorf("threw an error making the persister")
+	}
+
+	testutils.MigrateModels(pg.DB) // nolint: errcheck
+
+	defer pg.DB.Close()
+
+	cleaner := testutils.DeleteCreatedEntities(pg.DB)
+	defer cleaner()
+
+	narticle := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Title:        "context stuff",
+			CanonicalURL: "https://newstuff.bz/contextarticle",
+		},
+		NewsroomAddress: "0x7c722B8AC728aDd7780a66017e8daDBa530EE261",
+	}
+	if err := pg.CreateArticle(narticle); err != nil {
+		t.Errorf("should have created article: err: %v", err)
+	}
+
+	ctx := context.Background()
+	found, err := pg.ArticleByIDContext(ctx, narticle.ID)
+	if err != nil {
+		t.Errorf("should have found the article: err: %v", err)
+	} else if found.ArticleMetadata.Title != "context stuff" {
+		t.Errorf("should have returned the article: %v", found.ArticleMetadata.Title)
+	}
+
+	narticle.ArticleMetadata.Title = "updated context stuff"
+	if err := pg.UpdateArticleContext(ctx, narticle); err != nil {
+		t.Errorf("should have updated the article in a transaction: err: %v", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := pg.ArticleByIDContext(cancelled, narticle.ID); err != context.Canceled {
+		t.Errorf("should have returned context.Canceled: err: %v", err)
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = pg.WithContext(timeout, func(p *article.GormPGPersister) error {
+		return p.DB.Exec("SELECT pg_sleep(5)").Error
+	})
+	if !gormutils.IsTimeout(gormutils.ContextError(timeout, err)) {
+		t.Errorf("should have returned a timeout error: err: %v", err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Errorf("should have aborted the query in flight")
+	}
+}
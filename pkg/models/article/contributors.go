@@ -0,0 +1,161 @@
+package article
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	carticle "github.com/joincivil/go-common/pkg/article"
+	"github.com/pkg/errors"
+)
+
+const upsertContributorQuery = `
+INSERT INTO contributors (created_at, updated_at, normalized_name, address, name)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (normalized_name, address) DO UPDATE SET updated_at = contributors.updated_at
+RETURNING id`
+
+// ContributorGorm is the schema for a contributor. Contributors are unique by their
+// normalized name and eth address, which is empty if it is not known. Articles only have
+// the name and role of their contributors, so contributors linked from articles have no
+// address.
+type ContributorGorm struct {
+	ID             uint `gorm:"primary_key"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	NormalizedName string `gorm:"not null;unique_index:idx_contributors_normalized_name_address"`
+	Address        string `gorm:"not null;unique_index:idx_contributors_normalized_name_address"`
+	// Name is the name the contributor was first seen with
+	Name string
+}
+
+// TableName sets the name of the corresponding table in the db
+func (ContributorGorm) TableName() string {
+	return "contributors"
+}
+
+// ArticleContributorGorm links an article to a contributor in a role
+type ArticleContributorGorm struct {
+	ID            uint   `gorm:"primary_key"`
+	ArticleID     uint   `gorm:"not null;unique_index:idx_article_contributors_article_id_contributor_id_role"`
+	ContributorID uint   `gorm:"not null;unique_index:idx_article_contributors_article_id_contributor_id_role;index"`
+	Role          string `gorm:"unique_index:idx_article_contributors_article_id_contributor_id_role"`
+}
+
+// TableName sets the name of the corresponding table in the db
+func (ArticleContributorGorm) TableName() string {
+	return "article_contributors"
+}
+
+// Contributor is a writer, editor or other contributor to articles
+type Contributor struct {
+	ID      uint
+	Name    string
+	Address string
+}
+
+// NormalizeContributorName returns the name used to dedupe contributors. The name is
+// lowercased and runs of whitespace are collapsed.
+func NormalizeContributorName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// ContributorsByName returns the contributors with the given name, one for each known
+// eth address and one without an address if there are articles without one.
+func (p *GormPGPersister) ContributorsByName(name string) ([]Contributor, error) {
+	contributorGorms := []ContributorGorm{}
+	err := p.DB.Where("normalized_name = ?", NormalizeContributorName(name)).
+		Order("address ASC").
+		Find(&contributorGorms).Error
+	if err != nil {
+		return nil, err
+	}
+	return convertContributorGorms(contributorGorms), nil
+}
+
+// ArticlesByContributor returns a page of the articles of the contributor across all
+// newsrooms, ordered like ListArticles. The filter further narrows down the articles
+// and can be nil.
+func (p *GormPGPersister) ArticlesByContributor(contributorID uint, filter *ArticleFilter,
+	page *PageRequest) (*ArticleListing, error) {
+	db := p.DB.Model(&Gorm{}).Where(
+		"articles.id IN (SELECT article_id FROM article_contributors WHERE contributor_id = ?)",
+		contributorID,
+	)
+	return listArticles(db, filter, page)
+}
+
+// ContributorsForNewsroom returns the contributors to the articles of the newsroom,
+// ordered by name. Contributors to only deleted articles are not included.
+func (p *GormPGPersister) ContributorsForNewsroom(newsroomAddress string) ([]Contributor, error) {
+	contributorGorms := []ContributorGorm{}
+	err := p.DB.Where(
+		"id IN (SELECT article_contributors.contributor_id FROM article_contributors "+
+			"JOIN articles ON articles.id = article_contributors.article_id "+
+			"WHERE articles.newsroom_address = ? AND articles.deleted_at IS NULL)",
+		NormalizeNewsroomAddress(newsroomAddress),
+	).Order("normalized_name ASC, address ASC").Find(&contributorGorms).Error
+	if err != nil {
+		return nil, err
+	}
+	return convertContributorGorms(contributorGorms), nil
+}
+
+func convertContributorGorms(contributorGorms []ContributorGorm) []Contributor {
+	contributors := make([]Contributor, len(contributorGorms))
+	for i, c := range contributorGorms {
+		contributors[i] = Contributor{ID: c.ID, Name: c.Name, Address: c.Address}
+	}
+	return contributors
+}
+
+// syncArticleContributors replaces the contributor links of the article with the
+// contributors in the metadata
+func syncArticleContributors(tx *gorm.DB, articleID uint, article *carticle.Article) error {
+	err := tx.Where("article_id = ?", articleID).Delete(&ArticleContributorGorm{}).Error
+	if err != nil {
+		return errors.Wrap(err, "error deleting article contributors")
+	}
+
+	type linkKey struct {
+		contributorID uint
+		role          string
+	}
+	linked := map[linkKey]bool{}
+
+	for _, c := range article.ArticleMetadata.Contributors {
+		normalizedName := NormalizeContributorName(c.Name)
+		if normalizedName == "" {
+			continue
+		}
+
+		contributorID, err := upsertContributor(tx, normalizedName, "", strings.TrimSpace(c.Name))
+		if err != nil {
+			return err
+		}
+
+		key := linkKey{contributorID: contributorID, role: c.Role}
+		if linked[key] {
+			continue
+		}
+		linked[key] = true
+
+		link := ArticleContributorGorm{ArticleID: articleID, ContributorID: contributorID, Role: c.Role}
+		if err := tx.Create(&link).Error; err != nil {
+			return errors.Wrap(err, "error linking article contributor")
+		}
+	}
+
+	return nil
+}
+
+// upsertContributor returns the ID of the contributor, creating it if it does not exist
+func upsertContributor(tx *gorm.DB, normalizedName string, address string, name string) (uint, error) {
+	now := gorm.NowFunc()
+	var id uint
+	err := tx.Raw(upsertContributorQuery, now, now, normalizedName, address, name).Row().Scan(&id)
+	if err != nil {
+		return 0, errors.Wrap(err, "error saving contributor")
+	}
+	return id, nil
+}
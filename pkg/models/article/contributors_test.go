@@ -0,0 +1,94 @@
+package article_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/joincivil/go-common-priv/pkg/models/article"
+	"github.com/joincivil/go-common-priv/pkg/models/testutils"
+	carticle "github.com/joincivil/go-common/pkg/article"
+)
+
+func TestArticleContributors(t *testing.T) {
+	creds := testutils.GetTestDBConnection()
+	pg, err := article.NewGormPGPersister(creds.Host, creds.Port, creds.User, creds.Password, creds.Dbname)
+
+	if err != nil {
+		fmt.Println(err)
+		t.Errorf("threw an error making the persister")
+	}
+
+	testutils.MigrateModels(pg.DB) // nolint: errcheck
+
+	defer pg.DB.Close()
+
+	cleaner := testutils.DeleteCreatedEntities(pg.DB)
+	defer cleaner()
+
+	// Contributors are saved with raw sql so they are not tracked by the cleaner
+	names := []string{"test writer", "test editor"}
+	defer pg.DB.Where("normalized_name IN (?)", names).Delete(&article.ContributorGorm{})
+
+	newsroomAddr := "0x7c722B8AC728aDd7780a66017e8daDBa530EE261"
+	otherNewsroomAddr := "0x3e39fa983abcd349d95aed608e798817397cf0d1"
+
+	first := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			CanonicalURL: "https://newstuff.bz/contributedarticle1",
+			Contributors: []carticle.Contributor{
+				{Name: "Test Writer", Role: "author"},
+				{Name: "Test Editor", Role: "editor"},
+			},
+		},
+		NewsroomAddress: newsroomAddr,
+	}
+	// The same contributor with a differently written name
+	second := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			CanonicalURL: "https://othernews.bz/contributedarticle2",
+			Contributors: []carticle.Contributor{{Name: "test  writer", Role: "author"}},
+		},
+		NewsroomAddress: otherNewsroomAddr,
+	}
+	for _, a := range []*carticle.Article{first, second} {
+		if err := pg.CreateArticle(a); err != nil {
+			t.Errorf("should have created article: err: %v", err)
+		}
+	}
+
+	writers, err := pg.ContributorsByName("TEST WRITER")
+	if err != nil {
+		t.Errorf("should not have returned error: err: %v", err)
+	}
+	if len(writers) != 1 || writers[0].Name != "Test Writer" || writers[0].Address != "" {
+		t.Fatalf("should have found the writer by the normalized name: %v", writers)
+	}
+
+	listing, err := pg.ArticlesByContributor(writers[0].ID, nil, nil)
+	if err != nil {
+		t.Errorf("should not have returned error: err: %v", err)
+	}
+	if len(listing.Articles) != 2 {
+		t.Errorf("should have found the articles of the writer across newsrooms: %v", len(listing.Articles))
+	}
+
+	contributors, err := pg.ContributorsForNewsroom(newsroomAddr)
+	if err != nil {
+		t.Errorf("should not have returned error: err: %v", err)
+	}
+	if len(contributors) != 2 || contributors[0].Name != "Test Editor" {
+		t.Errorf("should have returned the contributors of the newsroom by name: %v", contributors)
+	}
+
+	first.ArticleMetadata.Contributors = first.ArticleMetadata.Contributors[:1]
+	if err := pg.UpdateArticle(first); err != nil {
+		t.Errorf("should have updated article: err: %v", err)
+	}
+	contributors, err = pg.ContributorsForNewsroom(newsroomAddr)
+	if err != nil {
+		t.Errorf("should not have returned error: err: %v", err)
+	}
+	if len(contributors) != 1 {
+		t.Errorf("should have removed the editor on update: %v", contributors)
+	}
+}
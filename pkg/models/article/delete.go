@@ -0,0 +1,71 @@
+package article
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// lookupDB returns the db to use for a lookup with the given options
+func lookupDB(db *gorm.DB, opts []LookupOption) *gorm.DB {
+	if NewLookupOptions(opts...).IncludeDeleted {
+		return db.Unscoped()
+	}
+	return db
+}
+
+// DeleteArticle soft deletes the article with the given ID. The article is no longer
+// returned by lookups and listings unless deleted articles are included.
+// Returns ErrArticleNotFound if there is no article that is not already deleted.
+func (p *GormPGPersister) DeleteArticle(articleID uint) error {
+	result := p.DB.Where("id = ?", articleID).Delete(&Gorm{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrArticleNotFound
+	}
+	return nil
+}
+
+// RestoreArticle restores a soft deleted article with the given ID.
+// Returns ErrArticleNotFound if there is no deleted article.
+func (p *GormPGPersister) RestoreArticle(articleID uint) error {
+	result := p.DB.Unscoped().Model(&Gorm{}).
+		Where("id = ? AND deleted_at IS NOT NULL", articleID).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrArticleNotFound
+	}
+	return nil
+}
+
+// PurgeArticle permanently deletes the article with the given ID and all the data
+// stored with it, whether or not it was soft deleted.
+// Returns ErrArticleNotFound if there is no article.
+func (p *GormPGPersister) PurgeArticle(articleID uint) error {
+	return withTransaction(p.DB, func(tx *gorm.DB) error {
+		for _, related := range []interface{}{
+			&RevisionGorm{},
+			&BlockDataHistoryGorm{},
+			&ArticleTagGorm{},
+			&ArticleContributorGorm{},
+			&ArticleImageGorm{},
+		} {
+			err := tx.Unscoped().Where("article_id = ?", articleID).Delete(related).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		result := tx.Unscoped().Where("id = ?", articleID).Delete(&Gorm{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrArticleNotFound
+		}
+		return nil
+	})
+}
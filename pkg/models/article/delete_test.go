@@ -0,0 +1,109 @@
+package article_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/joincivil/go-common-priv/pkg/models/article"
+	"github.com/joincivil/go-common-priv/pkg/models/testutils"
+	carticle "github.com/joincivil/go-common/pkg/article"
+)
+
+func TestDeleteRestoreAndPurgeArticle(t *testing.T) {
+	creds := testutils.GetTestDBConnection()
+	pg, err := article.NewGormPGPersister(creds.Host, creds.Port, creds.User, creds.Password, creds.Dbname)
+
+	if err != nil {
+		fmt.Println(err)
+		t.Errorf("threw an error making the persister")
+	}
+
+	testutils.MigrateModels(pg.DB) // nolint: errcheck
+
+	defer pg.DB.Close()
+
+	cleaner := testutils.DeleteCreatedEntities(pg.DB)
+	defer cleaner()
+
+	newsroomAddr := "0x8c722B8AC728aDd7780a66017e8daDBa530EE261"
+	narticle := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Title:               "retracted stuff",
+			CanonicalURL:        "https://newstuff.bz/retractedarticle",
+			RevisionContentHash: "0xretracted",
+		},
+		NewsroomAddress: newsroomAddr,
+	}
+
+	if err := pg.CreateArticle(narticle); err != nil {
+		t.Fatalf("should have created article: err: %v", err)
+	}
+
+	if err := pg.DeleteArticle(narticle.ID); err != nil {
+		t.Errorf("should have deleted article: err: %v", err)
+	}
+
+	if _, err := pg.ArticleByID(narticle.ID); err == nil {
+		t.Errorf("should not have found the deleted article")
+	}
+	if _, err := pg.ArticleByCanonicalURL("https://newstuff.bz/retractedarticle"); err != article.ErrArticleNotFound {
+		t.Errorf("should not have found the deleted article by url: err: %v", err)
+	}
+
+	deleted, err := pg.ArticleByID(narticle.ID, article.IncludeDeleted())
+	if err != nil {
+		t.Errorf("should have found the deleted article: err: %v", err)
+	} else if deleted.ArticleMetadata.Title != "retracted stuff" {
+		t.Errorf("should have kept the data of the deleted article")
+	}
+
+	listing, err := pg.ListArticles(&article.ArticleFilter{NewsroomAddress: newsroomAddr}, nil)
+	if err != nil {
+		t.Errorf("should have listed articles: err: %v", err)
+	}
+	if len(listing.Articles) != 0 {
+		t.Errorf("should not have listed the deleted article")
+	}
+
+	listing, err = pg.ListArticles(&article.ArticleFilter{
+		NewsroomAddress: newsroomAddr,
+		IncludeDeleted:  true,
+	}, nil)
+	if err != nil {
+		t.Errorf("should have listed articles: err: %v", err)
+	}
+	if len(listing.Articles) != 1 {
+		t.Errorf("should have listed the deleted article")
+	}
+
+	if err := pg.DeleteArticle(narticle.ID); err != article.ErrArticleNotFound {
+		t.Errorf("should have returned ErrArticleNotFound for deleted article: err: %v", err)
+	}
+
+	if err := pg.RestoreArticle(narticle.ID); err != nil {
+		t.Errorf("should have restored article: err: %v", err)
+	}
+	if _, err := pg.ArticleByID(narticle.ID); err != nil {
+		t.Errorf("should have found the restored article: err: %v", err)
+	}
+	if err := pg.RestoreArticle(narticle.ID); err != article.ErrArticleNotFound {
+		t.Errorf("should have returned ErrArticleNotFound for restored article: err: %v", err)
+	}
+
+	if err := pg.PurgeArticle(narticle.ID); err != nil {
+		t.Errorf("should have purged article: err: %v", err)
+	}
+	if _, err := pg.ArticleByID(narticle.ID, article.IncludeDeleted()); err == nil {
+		t.Errorf("should not have found the purged article")
+	}
+	revisions, err := pg.ArticleRevisions(narticle.ID)
+	if err != nil {
+		t.Errorf("should have retrieved revisions: err: %v", err)
+	}
+	if len(revisions) != 0 {
+		t.Errorf("should have purged the revisions of the article")
+	}
+	if err := pg.PurgeArticle(narticle.ID); err != article.ErrArticleNotFound {
+		t.Errorf("should have returned ErrArticleNotFound for purged article: err: %v", err)
+	}
+}
@@ -0,0 +1,350 @@
+package article
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	carticle "github.com/joincivil/go-common/pkg/article"
+	"github.com/pkg/errors"
+)
+
+// ArticleDiff is the set of changes between two versions of an article
+type ArticleDiff struct {
+	Fields       []FieldChange      `json:"fields,omitempty"`
+	Tags         TagChanges         `json:"tags"`
+	Contributors ContributorChanges `json:"contributors"`
+	Images       ImageChanges       `json:"images"`
+	RawJSONPatch []PatchOperation   `json:"rawJsonPatch,omitempty"`
+}
+
+// IsEmpty returns true if there are no changes in the diff
+func (d *ArticleDiff) IsEmpty() bool {
+	return len(d.Fields) == 0 &&
+		len(d.Tags.Added) == 0 && len(d.Tags.Removed) == 0 &&
+		len(d.Contributors.Added) == 0 && len(d.Contributors.Removed) == 0 &&
+		len(d.Contributors.Changed) == 0 &&
+		len(d.Images.Added) == 0 && len(d.Images.Removed) == 0 &&
+		len(d.Images.Changed) == 0 &&
+		len(d.RawJSONPatch) == 0
+}
+
+// FieldChange is a change to a single valued metadata field
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// TagChanges are the tags added and removed
+type TagChanges struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// ContributorChange is a contributor whose role has changed
+type ContributorChange struct {
+	From carticle.Contributor `json:"from"`
+	To   carticle.Contributor `json:"to"`
+}
+
+// ContributorChanges are the contributors added, removed and changed. Contributors
+// are matched by name, preferring a match with the same role.
+type ContributorChanges struct {
+	Added   []carticle.Contributor `json:"added,omitempty"`
+	Removed []carticle.Contributor `json:"removed,omitempty"`
+	Changed []ContributorChange    `json:"changed,omitempty"`
+}
+
+// ImageChange is an image whose URL, hash or dimensions have changed
+type ImageChange struct {
+	From carticle.Image `json:"from"`
+	To   carticle.Image `json:"to"`
+}
+
+// ImageChanges are the images added, removed and changed. Images are matched by
+// hash, or by URL if either image has no hash.
+type ImageChanges struct {
+	Added   []carticle.Image `json:"added,omitempty"`
+	Removed []carticle.Image `json:"removed,omitempty"`
+	Changed []ImageChange    `json:"changed,omitempty"`
+}
+
+// PatchOperation is a JSON patch (RFC 6902) operation
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// MarshalJSON always includes the value of add, replace and test operations, which
+// RFC 6902 requires even when the value is null
+func (o PatchOperation) MarshalJSON() ([]byte, error) {
+	switch o.Op {
+	case "add", "replace", "test":
+		return json.Marshal(struct {
+			Op    string      `json:"op"`
+			Path  string      `json:"path"`
+			Value interface{} `json:"value"`
+		}{o.Op, o.Path, o.Value})
+	}
+	return json.Marshal(struct {
+		Op   string `json:"op"`
+		Path string `json:"path"`
+	}{o.Op, o.Path})
+}
+
+// DiffArticles returns the changes to get from article a to article b
+func DiffArticles(a, b *carticle.Article) (*ArticleDiff, error) {
+	diff := &ArticleDiff{}
+	am := a.ArticleMetadata
+	bm := b.ArticleMetadata
+
+	diff.Fields = diffFields(am, bm)
+	diff.Tags = diffTags(am.Tags, bm.Tags)
+	diff.Contributors = diffContributors(am.Contributors, bm.Contributors)
+	diff.Images = diffImages(am.Images, bm.Images)
+
+	patch, err := diffRawJSON(a.RawJSON, b.RawJSON)
+	if err != nil {
+		return nil, err
+	}
+	diff.RawJSONPatch = patch
+
+	return diff, nil
+}
+
+func diffFields(a, b carticle.Metadata) []FieldChange {
+	fields := []struct {
+		name string
+		from string
+		to   string
+	}{
+		{"Title", a.Title, b.Title},
+		{"Description", a.Description, b.Description},
+		{"PrimaryTag", a.PrimaryTag, b.PrimaryTag},
+		{"Slug", a.Slug, b.Slug},
+		{"CanonicalURL", a.CanonicalURL, b.CanonicalURL},
+	}
+
+	changes := []FieldChange{}
+	for _, f := range fields {
+		if f.from != f.to {
+			changes = append(changes, FieldChange{Field: f.name, From: f.from, To: f.to})
+		}
+	}
+	return changes
+}
+
+func diffTags(a, b []string) TagChanges {
+	changes := TagChanges{}
+	aSet := map[string]bool{}
+	bSet := map[string]bool{}
+	for _, tag := range a {
+		aSet[tag] = true
+	}
+	for _, tag := range b {
+		bSet[tag] = true
+		if !aSet[tag] {
+			changes.Added = append(changes.Added, tag)
+		}
+	}
+	for _, tag := range a {
+		if !bSet[tag] {
+			changes.Removed = append(changes.Removed, tag)
+		}
+	}
+	return changes
+}
+
+func contributorKey(c carticle.Contributor) string {
+	return strings.ToLower(strings.TrimSpace(c.Name))
+}
+
+func diffContributors(a, b []carticle.Contributor) ContributorChanges {
+	changes := ContributorChanges{}
+	matched := make([]bool, len(a))
+	matches := make([]int, len(b))
+
+	// Contributors with the same name and role are matched before the ones with only the
+	// same name, so contributors listed more than once with different roles are kept apart
+	match := func(sameRole bool) {
+		for j, c := range b {
+			if sameRole {
+				matches[j] = -1
+			} else if matches[j] >= 0 {
+				continue
+			}
+			for i, from := range a {
+				if !matched[i] && contributorKey(from) == contributorKey(c) && (!sameRole || from.Role == c.Role) {
+					matched[i] = true
+					matches[j] = i
+					break
+				}
+			}
+		}
+	}
+	match(true)
+	match(false)
+
+	for j, c := range b {
+		i := matches[j]
+		if i < 0 {
+			changes.Added = append(changes.Added, c)
+		} else if a[i] != c {
+			changes.Changed = append(changes.Changed, ContributorChange{From: a[i], To: c})
+		}
+	}
+	for i, c := range a {
+		if !matched[i] {
+			changes.Removed = append(changes.Removed, c)
+		}
+	}
+	return changes
+}
+
+func diffImages(a, b []carticle.Image) ImageChanges {
+	changes := ImageChanges{}
+	matched := make([]bool, len(a))
+
+	findMatch := func(img carticle.Image) int {
+		if img.Hash != "" {
+			for i, from := range a {
+				if !matched[i] && from.Hash == img.Hash {
+					return i
+				}
+			}
+		}
+		for i, from := range a {
+			if !matched[i] && from.URL == img.URL && (from.Hash == "" || img.Hash == "") {
+				return i
+			}
+		}
+		return -1
+	}
+
+	for _, img := range b {
+		i := findMatch(img)
+		if i < 0 {
+			changes.Added = append(changes.Added, img)
+			continue
+		}
+		matched[i] = true
+		if a[i] != img {
+			changes.Changed = append(changes.Changed, ImageChange{From: a[i], To: img})
+		}
+	}
+	for i, img := range a {
+		if !matched[i] {
+			changes.Removed = append(changes.Removed, img)
+		}
+	}
+	return changes
+}
+
+// diffRawJSON returns the JSON patch that turns document a into document b
+func diffRawJSON(a, b json.RawMessage) ([]PatchOperation, error) {
+	aDoc, err := decodeJSONDocument(a)
+	if err != nil {
+		return nil, errors.Wrap(err, "error decoding raw json")
+	}
+	bDoc, err := decodeJSONDocument(b)
+	if err != nil {
+		return nil, errors.Wrap(err, "error decoding raw json")
+	}
+
+	ops := []PatchOperation{}
+	if aDoc == nil || bDoc == nil {
+		if aDoc != nil || bDoc != nil {
+			ops = append(ops, PatchOperation{Op: "replace", Path: "", Value: bDoc})
+		}
+		return ops, nil
+	}
+
+	return diffJSONValues("", aDoc, bDoc, ops), nil
+}
+
+func decodeJSONDocument(raw json.RawMessage) (interface{}, error) {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return nil, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func diffJSONValues(path string, a, b interface{}, ops []PatchOperation) []PatchOperation {
+	switch aVal := a.(type) {
+	case map[string]interface{}:
+		bVal, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(aVal))
+		for key := range aVal {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			keyPath := path + "/" + escapeJSONPointer(key)
+			if bChild, ok := bVal[key]; ok {
+				ops = diffJSONValues(keyPath, aVal[key], bChild, ops)
+			} else {
+				ops = append(ops, PatchOperation{Op: "remove", Path: keyPath})
+			}
+		}
+		keys = keys[:0]
+		for key := range bVal {
+			if _, ok := aVal[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			ops = append(ops, PatchOperation{
+				Op:    "add",
+				Path:  path + "/" + escapeJSONPointer(key),
+				Value: bVal[key],
+			})
+		}
+		return ops
+
+	case []interface{}:
+		bVal, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+		common := len(aVal)
+		if len(bVal) < common {
+			common = len(bVal)
+		}
+		for i := 0; i < common; i++ {
+			ops = diffJSONValues(path+"/"+strconv.Itoa(i), aVal[i], bVal[i], ops)
+		}
+		for i := common; i < len(bVal); i++ {
+			ops = append(ops, PatchOperation{Op: "add", Path: path + "/" + strconv.Itoa(i), Value: bVal[i]})
+		}
+		// Remove from the end so the indices of the remaining elements stay valid
+		for i := len(aVal) - 1; i >= common; i-- {
+			ops = append(ops, PatchOperation{Op: "remove", Path: path + "/" + strconv.Itoa(i)})
+		}
+		return ops
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		ops = append(ops, PatchOperation{Op: "replace", Path: path, Value: b})
+	}
+	return ops
+}
+
+func escapeJSONPointer(token string) string {
+	token = strings.Replace(token, "~", "~0", -1)
+	return strings.Replace(token, "/", "~1", -1)
+}
@@ -0,0 +1,175 @@
+package article_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/joincivil/go-common-priv/pkg/models/article"
+	carticle "github.com/joincivil/go-common/pkg/article"
+)
+
+func TestDiffArticles(t *testing.T) {
+	a := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Title:       "old title",
+			Description: "same description",
+			Tags:        []string{"news", "politics"},
+			Contributors: []carticle.Contributor{
+				{Name: "Alice", Role: "author"},
+				{Name: "Bob", Role: "editor"},
+			},
+			Images: []carticle.Image{
+				{URL: "https://newstuff.bz/a.png", Hash: "0xa", H: 10, W: 10},
+				{URL: "https://newstuff.bz/b.png"},
+			},
+		},
+		RawJSON: []byte(`{"title": "old title", "tags": ["news", "politics"], "body": "text"}`),
+	}
+
+	b := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Title:       "new title",
+			Description: "same description",
+			Tags:        []string{"politics", "elections"},
+			Contributors: []carticle.Contributor{
+				{Name: "Carol", Role: "author"},
+				{Name: "alice", Role: "editor"},
+			},
+			Images: []carticle.Image{
+				{URL: "https://newstuff.bz/b.png", Hash: "0xb", H: 20, W: 20},
+				{URL: "https://cdn.newstuff.bz/a.png", Hash: "0xa", H: 10, W: 10},
+			},
+		},
+		RawJSON: []byte(`{"title": "new title", "tags": ["news"], "author": "Carol"}`),
+	}
+
+	diff, err := article.DiffArticles(a, b)
+	if err != nil {
+		t.Fatalf("should not have returned error: err: %v", err)
+	}
+
+	if len(diff.Fields) != 1 || diff.Fields[0].Field != "Title" || diff.Fields[0].To != "new title" {
+		t.Errorf("should have only changed the title: %v", diff.Fields)
+	}
+
+	if len(diff.Tags.Added) != 1 || diff.Tags.Added[0] != "elections" {
+		t.Errorf("should have added the elections tag: %v", diff.Tags.Added)
+	}
+	if len(diff.Tags.Removed) != 1 || diff.Tags.Removed[0] != "news" {
+		t.Errorf("should have removed the news tag: %v", diff.Tags.Removed)
+	}
+
+	if len(diff.Contributors.Added) != 1 || diff.Contributors.Added[0].Name != "Carol" {
+		t.Errorf("should have added Carol: %v", diff.Contributors.Added)
+	}
+	if len(diff.Contributors.Removed) != 1 || diff.Contributors.Removed[0].Name != "Bob" {
+		t.Errorf("should have removed Bob: %v", diff.Contributors.Removed)
+	}
+	if len(diff.Contributors.Changed) != 1 || diff.Contributors.Changed[0].To.Role != "editor" {
+		t.Errorf("should have changed the role of Alice regardless of position: %v", diff.Contributors.Changed)
+	}
+
+	if len(diff.Images.Added) != 0 || len(diff.Images.Removed) != 0 {
+		t.Errorf("should have matched the images by identity: %v", diff.Images)
+	}
+	if len(diff.Images.Changed) != 2 {
+		t.Errorf("should have changed both images: %v", diff.Images.Changed)
+	}
+
+	expectedPatch := []article.PatchOperation{
+		{Op: "remove", Path: "/body"},
+		{Op: "remove", Path: "/tags/1"},
+		{Op: "replace", Path: "/title", Value: "new title"},
+		{Op: "add", Path: "/author", Value: "Carol"},
+	}
+	patchJSON, _ := json.Marshal(diff.RawJSONPatch)
+	expectedJSON, _ := json.Marshal(expectedPatch)
+	if string(patchJSON) != string(expectedJSON) {
+		t.Errorf("unexpected raw json patch: %v", string(patchJSON))
+	}
+
+	if diff.IsEmpty() {
+		t.Errorf("diff should not be empty")
+	}
+}
+
+func TestDiffArticlesUnchanged(t *testing.T) {
+	a := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Title:        "title",
+			Tags:         []string{"news"},
+			Contributors: []carticle.Contributor{{Name: "Alice", Role: "author"}},
+		},
+		RawJSON: []byte(`{"title": "title", "count": 1}`),
+	}
+	b := &carticle.Article{
+		ArticleMetadata: a.ArticleMetadata,
+		RawJSON:         []byte(`{"count":1,"title":"title"}`),
+	}
+
+	diff, err := article.DiffArticles(a, b)
+	if err != nil {
+		t.Fatalf("should not have returned error: err: %v", err)
+	}
+	if !diff.IsEmpty() {
+		t.Errorf("diff should be empty: %v", diff)
+	}
+
+	b.RawJSON = []byte(`{not json`)
+	if _, err := article.DiffArticles(a, b); err == nil {
+		t.Errorf("should have returned error for invalid raw json")
+	}
+}
+
+func TestDiffArticlesNullValues(t *testing.T) {
+	a := &carticle.Article{RawJSON: []byte(`{"title": "title", "byline": "Alice"}`)}
+	b := &carticle.Article{RawJSON: []byte(`{"title": null, "byline": "Alice", "subtitle": null}`)}
+
+	diff, err := article.DiffArticles(a, b)
+	if err != nil {
+		t.Fatalf("should have diffed the articles: err: %v", err)
+	}
+
+	patchJSON, _ := json.Marshal(diff.RawJSONPatch)
+	expected := `[{"op":"replace","path":"/title","value":null},{"op":"add","path":"/subtitle","value":null}]`
+	if string(patchJSON) != expected {
+		t.Errorf("should have kept the null values in the patch: %v", string(patchJSON))
+	}
+}
+
+func TestDiffArticlesContributorRoles(t *testing.T) {
+	a := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Contributors: []carticle.Contributor{
+				{Name: "Alice", Role: "author"},
+				{Name: "Alice", Role: "photographer"},
+			},
+		},
+	}
+	b := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Contributors: []carticle.Contributor{
+				{Name: "Alice", Role: "photographer"},
+			},
+		},
+	}
+
+	diff, err := article.DiffArticles(a, b)
+	if err != nil {
+		t.Fatalf("should have diffed the articles: err: %v", err)
+	}
+	if len(diff.Contributors.Changed) != 0 || len(diff.Contributors.Added) != 0 {
+		t.Errorf("should have matched the contributor with the same role: %v", diff.Contributors)
+	}
+	if len(diff.Contributors.Removed) != 1 || diff.Contributors.Removed[0].Role != "author" {
+		t.Errorf("should have removed Alice as author: %v", diff.Contributors.Removed)
+	}
+
+	diff, err = article.DiffArticles(b, a)
+	if err != nil {
+		t.Fatalf("should have diffed the articles: err: %v", err)
+	}
+	if len(diff.Contributors.Added) != 1 || diff.Contributors.Added[0].Role != "author" {
+		t.Errorf("should have added Alice as author: %v", diff.Contributors.Added)
+	}
+}
@@ -0,0 +1,249 @@
+package article
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	carticle "github.com/joincivil/go-common/pkg/article"
+	"github.com/pkg/errors"
+)
+
+const upsertImageQuery = `
+INSERT INTO article_images (created_at, updated_at, image_key, hash, url, h, w)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (image_key) DO UPDATE SET
+	updated_at = EXCLUDED.updated_at,
+	url = CASE WHEN article_images.url = '' THEN EXCLUDED.url ELSE article_images.url END,
+	h = CASE WHEN article_images.h = 0 THEN EXCLUDED.h ELSE article_images.h END,
+	w = CASE WHEN article_images.w = 0 THEN EXCLUDED.w ELSE article_images.w END
+RETURNING id`
+
+var (
+	// ErrImageNotFound indicates that no image was found with the ID
+	ErrImageNotFound = errors.New("image not found")
+)
+
+// ImageGorm is the schema for an image used in articles. Images are stored once per
+// content hash, or once per URL for images without a hash.
+type ImageGorm struct {
+	ID        uint `gorm:"primary_key"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	// ImageKey is the hash of the image, or its URL prefixed with "url:" if there is no hash
+	ImageKey string `gorm:"not null;unique_index"`
+	Hash     string
+	URL      string `gorm:"index"`
+	H        int
+	W        int
+}
+
+// TableName sets the name of the corresponding table in the db
+func (ImageGorm) TableName() string {
+	return "article_images"
+}
+
+// ConvertToImage returns the image as the public image struct
+func (i *ImageGorm) ConvertToImage() Image {
+	return Image{ID: i.ID, Image: carticle.Image{URL: i.URL, Hash: i.Hash, H: i.H, W: i.W}}
+}
+
+// ArticleImageGorm links an article to an image at its position in the article metadata
+type ArticleImageGorm struct {
+	ID        uint `gorm:"primary_key"`
+	ArticleID uint `gorm:"not null;unique_index:idx_article_image_links_article_id_image_id"`
+	ImageID   uint `gorm:"not null;unique_index:idx_article_image_links_article_id_image_id;index"`
+	Position  int
+}
+
+// TableName sets the name of the corresponding table in the db
+func (ArticleImageGorm) TableName() string {
+	return "article_image_links"
+}
+
+// Image is a stored image with its ID
+type Image struct {
+	carticle.Image
+	ID uint
+}
+
+func imageKey(hash string, url string) string {
+	if hash != "" {
+		return hash
+	}
+	return "url:" + url
+}
+
+// ImagesForArticle returns the images of the article in the order of the article metadata
+func (p *GormPGPersister) ImagesForArticle(articleID uint) ([]Image, error) {
+	imageGorms := []ImageGorm{}
+	err := p.DB.Joins("JOIN article_image_links ON article_image_links.image_id = article_images.id").
+		Where("article_image_links.article_id = ?", articleID).
+		Order("article_image_links.position ASC").
+		Find(&imageGorms).Error
+	if err != nil {
+		return nil, err
+	}
+	return convertImageGorms(imageGorms), nil
+}
+
+// ImagesMissingHash returns up to limit images without a content hash, ordered by ID.
+// Pass the ID of the last image returned as afterID to get the next batch.
+func (p *GormPGPersister) ImagesMissingHash(afterID uint, limit int) ([]Image, error) {
+	return p.imagesWhere("hash = ''", afterID, limit)
+}
+
+// ImagesMissingDimensions returns up to limit images without a height or width, ordered
+// by ID. Pass the ID of the last image returned as afterID to get the next batch.
+func (p *GormPGPersister) ImagesMissingDimensions(afterID uint, limit int) ([]Image, error) {
+	return p.imagesWhere("h = 0 OR w = 0", afterID, limit)
+}
+
+func (p *GormPGPersister) imagesWhere(condition string, afterID uint, limit int) ([]Image, error) {
+	imageGorms := []ImageGorm{}
+	err := p.DB.Where(condition).
+		Where("id > ?", afterID).
+		Order("id ASC").
+		Limit(pageLimit(&PageRequest{Limit: limit})).
+		Find(&imageGorms).Error
+	if err != nil {
+		return nil, err
+	}
+	return convertImageGorms(imageGorms), nil
+}
+
+// UpdateImage sets the hash and dimensions of the stored image with the ID of the given
+// image, for backfilling images that were saved without them. If another image already
+// has the hash, the images are merged into that one and the ID is updated to its ID.
+// Returns ErrImageNotFound if there is no image with the ID.
+func (p *GormPGPersister) UpdateImage(image *Image) error {
+	return withTransaction(p.DB, func(tx *gorm.DB) error {
+		existing := ImageGorm{}
+		if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&existing, image.ID).Error; err != nil {
+			if gorm.IsRecordNotFoundError(err) {
+				return ErrImageNotFound
+			}
+			return err
+		}
+
+		hash := existing.Hash
+		if image.Hash != "" {
+			hash = image.Hash
+		}
+		key := imageKey(hash, existing.URL)
+
+		target := existing
+		if key != existing.ImageKey {
+			other := ImageGorm{}
+			err := tx.Set("gorm:query_option", "FOR UPDATE").
+				Where("image_key = ?", key).
+				First(&other).Error
+			if err == nil {
+				if err := mergeImageLinks(tx, existing.ID, other.ID); err != nil {
+					return err
+				}
+				target = other
+			} else if !gorm.IsRecordNotFoundError(err) {
+				return err
+			}
+		}
+
+		updates := map[string]interface{}{"image_key": key, "hash": hash}
+		if image.H != 0 {
+			updates["h"] = image.H
+		}
+		if image.W != 0 {
+			updates["w"] = image.W
+		}
+		if err := tx.Model(&target).Updates(updates).Error; err != nil {
+			return errors.Wrap(err, "error updating image")
+		}
+
+		*image = target.ConvertToImage()
+		return nil
+	})
+}
+
+// mergeImageLinks moves the article links of an image to another image and deletes it
+func mergeImageLinks(tx *gorm.DB, fromID uint, toID uint) error {
+	err := tx.Model(&ArticleImageGorm{}).
+		Where("image_id = ?", fromID).
+		Where("article_id NOT IN (SELECT article_id FROM article_image_links WHERE image_id = ?)", toID).
+		Update("image_id", toID).Error
+	if err != nil {
+		return errors.Wrap(err, "error moving image links")
+	}
+	if err := tx.Where("image_id = ?", fromID).Delete(&ArticleImageGorm{}).Error; err != nil {
+		return errors.Wrap(err, "error deleting image links")
+	}
+	return errors.Wrap(tx.Delete(&ImageGorm{ID: fromID}).Error, "error deleting image")
+}
+
+func convertImageGorms(imageGorms []ImageGorm) []Image {
+	images := make([]Image, len(imageGorms))
+	for i := range imageGorms {
+		images[i] = imageGorms[i].ConvertToImage()
+	}
+	return images
+}
+
+// syncArticleImages replaces the image links of the article with the images in the metadata
+func syncArticleImages(tx *gorm.DB, articleID uint, metadata *carticle.Metadata) error {
+	err := tx.Where("article_id = ?", articleID).Delete(&ArticleImageGorm{}).Error
+	if err != nil {
+		return errors.Wrap(err, "error deleting article images")
+	}
+
+	linked := map[uint]bool{}
+	for position, img := range metadata.Images {
+		if img.Hash == "" && img.URL == "" {
+			continue
+		}
+
+		imageID, err := saveImage(tx, img)
+		if err != nil {
+			return err
+		}
+		if linked[imageID] {
+			continue
+		}
+		linked[imageID] = true
+
+		link := ArticleImageGorm{ArticleID: articleID, ImageID: imageID, Position: position}
+		if err := tx.Create(&link).Error; err != nil {
+			return errors.Wrap(err, "error linking article image")
+		}
+	}
+
+	return nil
+}
+
+// saveImage returns the ID of the stored image, creating it if it does not exist. An image
+// without a hash is matched to an image with the same URL that has since been hashed.
+func saveImage(tx *gorm.DB, img carticle.Image) (uint, error) {
+	if img.Hash == "" {
+		hashed := ImageGorm{}
+		err := tx.Where("url = ? AND hash <> ''", img.URL).Order("id ASC").First(&hashed).Error
+		if err == nil {
+			return hashed.ID, nil
+		} else if !gorm.IsRecordNotFoundError(err) {
+			return 0, errors.Wrap(err, "error finding image")
+		}
+	}
+
+	now := gorm.NowFunc()
+	var id uint
+	err := tx.Raw(
+		upsertImageQuery,
+		now,
+		now,
+		imageKey(img.Hash, img.URL),
+		img.Hash,
+		img.URL,
+		img.H,
+		img.W,
+	).Row().Scan(&id)
+	if err != nil {
+		return 0, errors.Wrap(err, "error saving image")
+	}
+	return id, nil
+}
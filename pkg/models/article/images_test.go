@@ -0,0 +1,114 @@
+package article_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/joincivil/go-common-priv/pkg/models/article"
+	"github.com/joincivil/go-common-priv/pkg/models/testutils"
+	carticle "github.com/joincivil/go-common/pkg/article"
+)
+
+func TestArticleImages(t *testing.T) {
+	creds := testutils.GetTestDBConnection()
+	pg, err := article.NewGormPGPersister(creds.Host, creds.Port, creds.User, creds.Password, creds.Dbname)
+
+	if err != nil {
+		fmt.Println(err)
+		t.Errorf("threw an error making the persister")
+	}
+
+	testutils.MigrateModels(pg.DB) // nolint: errcheck
+
+	defer pg.DB.Close()
+
+	cleaner := testutils.DeleteCreatedEntities(pg.DB)
+	defer cleaner()
+
+	// Images are saved with raw sql so they are not tracked by the cleaner
+	urls := []string{"https://newstuff.bz/testimage1.png", "https://newstuff.bz/testimage2.png"}
+	defer pg.DB.Where("url IN (?)", urls).Delete(&article.ImageGorm{})
+
+	newsroomAddr := "0x7c722B8AC728aDd7780a66017e8daDBa530EE261"
+	first := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			CanonicalURL: "https://newstuff.bz/imagearticle1",
+			Images: []carticle.Image{
+				{URL: urls[0], Hash: "0xtestimage1"},
+				{URL: urls[1]},
+			},
+		},
+		NewsroomAddress: newsroomAddr,
+	}
+	second := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			CanonicalURL: "https://newstuff.bz/imagearticle2",
+			Images: []carticle.Image{
+				{URL: "https://cdn.newstuff.bz/testimage1.png", Hash: "0xtestimage1", H: 100, W: 200},
+			},
+		},
+		NewsroomAddress: newsroomAddr,
+	}
+	for _, a := range []*carticle.Article{first, second} {
+		if err := pg.CreateArticle(a); err != nil {
+			t.Errorf("should have created article: err: %v", err)
+		}
+	}
+
+	images, err := pg.ImagesForArticle(first.ID)
+	if err != nil {
+		t.Errorf("should not have returned error: err: %v", err)
+	}
+	if len(images) != 2 || images[0].Hash != "0xtestimage1" || images[1].URL != urls[1] {
+		t.Fatalf("should have returned the images of the article in order: %v", images)
+	}
+	if images[0].URL != urls[0] || images[0].H != 100 || images[0].W != 200 {
+		t.Errorf("should have stored the image once and filled in the dimensions: %v", images[0])
+	}
+
+	missingHash, err := pg.ImagesMissingHash(0, 500)
+	if err != nil {
+		t.Errorf("should not have returned error: err: %v", err)
+	}
+	var unhashed *article.Image
+	for i := range missingHash {
+		if missingHash[i].URL == urls[1] {
+			unhashed = &missingHash[i]
+		}
+	}
+	if unhashed == nil {
+		t.Fatalf("should have returned the image without a hash")
+	}
+
+	missingDims, err := pg.ImagesMissingDimensions(0, 500)
+	if err != nil {
+		t.Errorf("should not have returned error: err: %v", err)
+	}
+	for _, img := range missingDims {
+		if img.ID == images[0].ID {
+			t.Errorf("should not have returned the image with dimensions")
+		}
+	}
+
+	// Backfilling the hash of an image that is already stored merges the two
+	unhashed.Hash = "0xtestimage1"
+	unhashed.H = 50
+	if err := pg.UpdateImage(unhashed); err != nil {
+		t.Errorf("should have updated the image: err: %v", err)
+	}
+	if unhashed.ID != images[0].ID {
+		t.Errorf("should have merged the image into the image with the same hash")
+	}
+
+	images, err = pg.ImagesForArticle(first.ID)
+	if err != nil {
+		t.Errorf("should not have returned error: err: %v", err)
+	}
+	if len(images) != 1 {
+		t.Errorf("should have merged the image links of the article: %v", images)
+	}
+
+	if err := pg.UpdateImage(&article.Image{ID: 0}); err != article.ErrImageNotFound {
+		t.Errorf("should have returned ErrImageNotFound: err: %v", err)
+	}
+}
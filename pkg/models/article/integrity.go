@@ -0,0 +1,114 @@
+package article
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/joincivil/go-common-priv/pkg/models/internal/listcursor"
+	carticle "github.com/joincivil/go-common/pkg/article"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrContentHashMismatch indicates that the raw json of an article does not hash to
+	// the RevisionContentHash in its metadata
+	ErrContentHashMismatch = errors.New("content hash does not match raw json")
+)
+
+// ContentHashMismatch is an article whose raw json does not match its content hash
+type ContentHashMismatch struct {
+	ArticleID       uint
+	NewsroomAddress string
+	ExpectedHash    string
+	ComputedHash    string
+}
+
+// ComputeContentHash returns the keccak256 hash of the compact JSON serialization of the
+// raw json document, as a 0x prefixed hex string. Compacting only drops whitespace, so key
+// order and escaping have to match the document that was hashed.
+//
+// The hashing done by the Civil publisher is not part of this repo and this scheme has not
+// been checked against it, other than producing the bytes32 contentHash the Newsroom
+// contract stores. Check it against published revisions before enabling
+// RequireContentHashMatch.
+func ComputeContentHash(rawJSON json.RawMessage) (string, error) {
+	compacted := &bytes.Buffer{}
+	if err := json.Compact(compacted, rawJSON); err != nil {
+		return "", errors.Wrap(err, "error compacting raw json")
+	}
+	return crypto.Keccak256Hash(compacted.Bytes()).Hex(), nil
+}
+
+// VerifyContentHash checks that the raw json of the article matches the RevisionContentHash
+// in its metadata. Returns an error with ErrContentHashMismatch as the cause if it does not.
+// Articles without a content hash or raw json are not checked.
+func VerifyContentHash(article *carticle.Article) error {
+	_, err := checkContentHash(article)
+	return err
+}
+
+// checkContentHash verifies the content hash of the article and returns the computed hash
+func checkContentHash(article *carticle.Article) (string, error) {
+	expected := article.ArticleMetadata.RevisionContentHash
+	if expected == "" || len(article.RawJSON) == 0 {
+		return "", nil
+	}
+
+	computed, err := ComputeContentHash(article.RawJSON)
+	if err != nil {
+		return "", err
+	}
+	if !strings.EqualFold(expected, computed) {
+		return computed, errors.Wrapf(ErrContentHashMismatch, "expected %v, computed %v", expected, computed)
+	}
+	return computed, nil
+}
+
+// FindContentHashMismatches checks every article matching the filter and returns the
+// articles whose raw json does not match their content hash. The raw json is hashed as it
+// was saved. Articles saved before the raw_json_text column existed only have the jsonb
+// serialization, so documents with more than one key may be reported for them.
+func (p *GormPGPersister) FindContentHashMismatches(filter *ArticleFilter) ([]ContentHashMismatch, error) {
+	mismatches := []ContentHashMismatch{}
+	page := &PageRequest{Limit: listcursor.MaxLimit}
+
+	for {
+		listing, err := p.ListArticles(filter, page)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range listing.Articles {
+			a := &listing.Articles[i]
+			computed, err := checkContentHash(a)
+			if err == nil {
+				continue
+			}
+			if errors.Cause(err) != ErrContentHashMismatch {
+				return nil, errors.Wrapf(err, "error verifying article %v", a.ID)
+			}
+
+			mismatches = append(mismatches, ContentHashMismatch{
+				ArticleID:       a.ID,
+				NewsroomAddress: a.NewsroomAddress,
+				ExpectedHash:    a.ArticleMetadata.RevisionContentHash,
+				ComputedHash:    computed,
+			})
+		}
+
+		if listing.NextCursor == "" {
+			return mismatches, nil
+		}
+		page.Cursor = listing.NextCursor
+	}
+}
+
+// verifyContentHash checks the content hash if the persister requires matching hashes
+func (p *GormPGPersister) verifyContentHash(article *carticle.Article) error {
+	if !p.RequireContentHashMatch {
+		return nil
+	}
+	return VerifyContentHash(article)
+}
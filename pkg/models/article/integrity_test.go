@@ -0,0 +1,138 @@
+package article_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/joincivil/go-common-priv/pkg/models/article"
+	"github.com/joincivil/go-common-priv/pkg/models/testutils"
+	carticle "github.com/joincivil/go-common/pkg/article"
+	"github.com/pkg/errors"
+)
+
+func TestComputeContentHash(t *testing.T) {
+	expected := crypto.Keccak256Hash([]byte(`{"title":"hashed","tags":["a","b"]}`)).Hex()
+
+	hash, err := article.ComputeContentHash([]byte(`{
+		"title": "hashed",
+		"tags": ["a", "b"]
+	}`))
+	if err != nil {
+		t.Errorf("should not have returned error: err: %v", err)
+	}
+	if hash != expected {
+		t.Errorf("should have hashed the compact json: %v != %v", hash, expected)
+	}
+
+	if _, err := article.ComputeContentHash([]byte(`{not json`)); err == nil {
+		t.Errorf("should have returned error for invalid json")
+	}
+}
+
+func TestVerifyContentHash(t *testing.T) {
+	rawJSON := []byte(`{"title": "verified"}`)
+	hash, _ := article.ComputeContentHash(rawJSON)
+
+	narticle := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{RevisionContentHash: hash},
+		RawJSON:         rawJSON,
+	}
+	if err := article.VerifyContentHash(narticle); err != nil {
+		t.Errorf("should have verified the content hash: err: %v", err)
+	}
+
+	narticle.RawJSON = []byte(`{"title": "tampered"}`)
+	err := article.VerifyContentHash(narticle)
+	if errors.Cause(err) != article.ErrContentHashMismatch {
+		t.Errorf("should have returned ErrContentHashMismatch: err: %v", err)
+	}
+
+	narticle.ArticleMetadata.RevisionContentHash = ""
+	if err := article.VerifyContentHash(narticle); err != nil {
+		t.Errorf("should not verify articles without a content hash: err: %v", err)
+	}
+}
+
+func TestRequireContentHashMatch(t *testing.T) {
+	creds := testutils.GetTestDBConnection()
+	pg, err := article.NewGormPGPersister(creds.Host, creds.Port, creds.User, creds.Password, creds.Dbname)
+
+	if err != nil {
+		fmt.Println(err)
+		t.Errorf("threw an error making the persister")
+	}
+
+	testutils.MigrateModels(pg.DB) // nolint: errcheck
+
+	defer pg.DB.Close()
+
+	cleaner := testutils.DeleteCreatedEntities(pg.DB)
+	defer cleaner()
+
+	newsroomAddr := "0x7c722B8AC728aDd7780a66017e8daDBa530EE261"
+	tampered := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Title:               "tampered stuff",
+			CanonicalURL:        "https://newstuff.bz/tamperedarticle",
+			RevisionContentHash: "0x1234",
+		},
+		NewsroomAddress: newsroomAddr,
+		RawJSON:         []byte(`{"title": "tampered stuff"}`),
+	}
+
+	if err := pg.CreateArticle(tampered); err != nil {
+		t.Errorf("should have created article without verification: err: %v", err)
+	}
+
+	mismatches, err := pg.FindContentHashMismatches(&article.ArticleFilter{NewsroomAddress: newsroomAddr})
+	if err != nil {
+		t.Errorf("should have checked the articles: err: %v", err)
+	}
+	if len(mismatches) != 1 || mismatches[0].ArticleID != tampered.ID {
+		t.Errorf("should have reported the tampered article: %v", mismatches)
+	}
+
+	pg.RequireContentHashMatch = true
+
+	tamperedID := tampered.ID
+	tampered.ID = 0
+	err = pg.CreateArticle(tampered)
+	if errors.Cause(err) != article.ErrContentHashMismatch {
+		t.Errorf("should have failed to create the tampered article: err: %v", err)
+	}
+
+	verified := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Title:        "verified stuff",
+			CanonicalURL: "https://newstuff.bz/verifiedarticle",
+		},
+		NewsroomAddress: newsroomAddr,
+		// jsonb would reorder the keys and drop the whitespace
+		RawJSON: []byte(`{"title": "verified stuff", "body":  "stuff", "author": {"name": "Alice", "id": 1}}`),
+	}
+	verified.ArticleMetadata.RevisionContentHash, _ = article.ComputeContentHash(verified.RawJSON)
+
+	if err := pg.CreateArticle(verified); err != nil {
+		t.Errorf("should have created the verified article: err: %v", err)
+	}
+
+	found, err := pg.ArticleByID(verified.ID)
+	if err != nil {
+		t.Fatalf("should have found the verified article: err: %v", err)
+	}
+	if string(found.RawJSON) != string(verified.RawJSON) {
+		t.Errorf("should have returned the raw json as it was saved: %v", string(found.RawJSON))
+	}
+	if err := article.VerifyContentHash(found); err != nil {
+		t.Errorf("should have verified the saved article: err: %v", err)
+	}
+
+	mismatches, err = pg.FindContentHashMismatches(&article.ArticleFilter{NewsroomAddress: newsroomAddr})
+	if err != nil {
+		t.Errorf("should have checked the articles: err: %v", err)
+	}
+	if len(mismatches) != 1 || mismatches[0].ArticleID != tamperedID {
+		t.Errorf("should not have reported the verified article with unsorted keys: %v", mismatches)
+	}
+}
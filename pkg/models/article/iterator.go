@@ -0,0 +1,106 @@
+package article
+
+import (
+	carticle "github.com/joincivil/go-common/pkg/article"
+)
+
+// Iterator walks over articles without loading them all into memory. Call Next before
+// each Article, and check Err once Next returns false.
+//
+//	it := persister.IterateArticles(filter, 0)
+//	defer it.Close()
+//	for it.Next() {
+//		process(it.Article())
+//	}
+//	if err := it.Err(); err != nil {
+//		return err
+//	}
+type Iterator interface {
+	// Next advances to the next article, returns false when there are no more
+	// articles or there was an error
+	Next() bool
+	// Article returns the current article
+	Article() *carticle.Article
+	// Err returns the error that stopped the iteration, if any
+	Err() error
+	// Close stops the iteration
+	Close() error
+}
+
+// ListFunc returns a page of articles, such as ListArticles with a fixed filter
+type ListFunc func(page *PageRequest) (*ArticleListing, error)
+
+// NewListIterator returns an Iterator that fetches the articles in batches of
+// batchSize using the list func. Only one batch is held in memory at a time.
+func NewListIterator(list ListFunc, batchSize int) Iterator {
+	return &listIterator{
+		list: list,
+		page: &PageRequest{Limit: pageLimit(&PageRequest{Limit: batchSize})},
+		pos:  -1,
+	}
+}
+
+type listIterator struct {
+	list    ListFunc
+	page    *PageRequest
+	batch   []carticle.Article
+	pos     int
+	fetched bool
+	done    bool
+	err     error
+}
+
+func (it *listIterator) Next() bool {
+	if it.done {
+		return false
+	}
+
+	it.pos++
+	for it.pos >= len(it.batch) {
+		if it.fetched && it.page.Cursor == "" {
+			it.done = true
+			it.batch = nil
+			return false
+		}
+
+		listing, err := it.list(it.page)
+		if err != nil {
+			it.err = err
+			it.done = true
+			it.batch = nil
+			return false
+		}
+		it.fetched = true
+		it.batch = listing.Articles
+		it.page.Cursor = listing.NextCursor
+		it.pos = 0
+	}
+
+	return true
+}
+
+func (it *listIterator) Article() *carticle.Article {
+	if it.pos < 0 || it.pos >= len(it.batch) {
+		return nil
+	}
+	return &it.batch[it.pos]
+}
+
+func (it *listIterator) Err() error {
+	return it.err
+}
+
+func (it *listIterator) Close() error {
+	it.done = true
+	it.batch = nil
+	return nil
+}
+
+// IterateArticles returns an Iterator over all the articles matching the filter, in the
+// order of ListArticles. Articles are fetched with keyset pagination in batches of
+// batchSize, or the default page size if batchSize is 0.
+func (p *GormPGPersister) IterateArticles(filter *ArticleFilter, batchSize int) Iterator {
+	return NewListIterator(func(page *PageRequest) (*ArticleListing, error) {
+		return p.ListArticles(filter, page)
+	}, batchSize)
+}
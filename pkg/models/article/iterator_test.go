@@ -0,0 +1,77 @@
+package article_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/joincivil/go-common-priv/pkg/models/article"
+	carticle "github.com/joincivil/go-common/pkg/article"
+	"github.com/pkg/errors"
+)
+
+// fakeList returns a ListFunc over n articles with IDs n to 1, and counts the calls
+func fakeList(n int, calls *int) article.ListFunc {
+	return func(page *article.PageRequest) (*article.ArticleListing, error) {
+		*calls++
+		start := 0
+		if page.Cursor != "" {
+			start, _ = strconv.Atoi(page.Cursor)
+		}
+		end := start + page.Limit
+		listing := &article.ArticleListing{}
+		if end < n {
+			listing.NextCursor = strconv.Itoa(end)
+		} else {
+			end = n
+		}
+		for i := start; i < end; i++ {
+			listing.Articles = append(listing.Articles, carticle.Article{ID: uint(n - i)})
+		}
+		return listing, nil
+	}
+}
+
+func TestListIterator(t *testing.T) {
+	calls := 0
+	it := article.NewListIterator(fakeList(7, &calls), 3)
+	defer it.Close() // nolint: errcheck
+
+	ids := []uint{}
+	for it.Next() {
+		ids = append(ids, it.Article().ID)
+	}
+	if it.Err() != nil {
+		t.Errorf("should not have returned error: err: %v", it.Err())
+	}
+	if len(ids) != 7 || ids[0] != 7 || ids[6] != 1 {
+		t.Errorf("should have iterated over all the articles in order: %v", ids)
+	}
+	if calls != 3 {
+		t.Errorf("should have fetched 3 batches: %v", calls)
+	}
+	if it.Next() {
+		t.Errorf("should not advance past the end")
+	}
+
+	calls = 0
+	it = article.NewListIterator(fakeList(0, &calls), 3)
+	if it.Next() || it.Article() != nil {
+		t.Errorf("should not have iterated over an empty listing")
+	}
+
+	calls = 0
+	it = article.NewListIterator(fakeList(7, &calls), 3)
+	it.Next()
+	it.Close() // nolint: errcheck
+	if it.Next() {
+		t.Errorf("should not advance after close")
+	}
+
+	listErr := errors.New("list failed")
+	it = article.NewListIterator(func(page *article.PageRequest) (*article.ArticleListing, error) {
+		return nil, listErr
+	}, 3)
+	if it.Next() || it.Err() != listErr {
+		t.Errorf("should have stopped with the list error: err: %v", it.Err())
+	}
+}
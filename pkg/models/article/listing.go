@@ -0,0 +1,135 @@
+package article
+
+import (
+	"encoding/json"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+
+	"github.com/joincivil/go-common-priv/pkg/models/internal/listcursor"
+)
+
+var (
+	// ErrInvalidCursor indicates that the page cursor could not be decoded
+	ErrInvalidCursor = listcursor.ErrInvalid
+)
+
+func pageLimit(page *PageRequest) int {
+	if page == nil {
+		return listcursor.Limit(0)
+	}
+	return listcursor.Limit(page.Limit)
+}
+
+// applyArticleFilter adds the conditions in the filter to the query
+func applyArticleFilter(db *gorm.DB, filter *ArticleFilter) (*gorm.DB, error) {
+	if filter == nil {
+		return db, nil
+	}
+
+	if filter.IncludeDeleted {
+		db = db.Unscoped()
+	}
+	if filter.NewsroomAddress != "" {
+		db = db.Where("articles.newsroom_address = ?", NormalizeNewsroomAddress(filter.NewsroomAddress))
+	}
+	if !filter.IndexedAfter.IsZero() {
+		db = db.Where("articles.indexed_timestamp >= ?", filter.IndexedAfter)
+	}
+	if !filter.IndexedBefore.IsZero() {
+		db = db.Where("articles.indexed_timestamp < ?", filter.IndexedBefore)
+	}
+	if !filter.PublishedAfter.IsZero() {
+		db = db.Where(
+			"(articles.article_metadata->>'OriginalPublishDate')::timestamptz >= ?",
+			filter.PublishedAfter,
+		)
+	}
+	if !filter.PublishedBefore.IsZero() {
+		db = db.Where(
+			"(articles.article_metadata->>'OriginalPublishDate')::timestamptz < ?",
+			filter.PublishedBefore,
+		)
+	}
+	if filter.Tag != "" {
+		tagJSON, err := json.Marshal([]string{filter.Tag})
+		if err != nil {
+			return nil, errors.Wrap(err, "error marshalling tag")
+		}
+		db = db.Where(
+			"(articles.article_metadata->'Tags' @> ?::jsonb OR articles.article_metadata->>'PrimaryTag' = ?)",
+			string(tagJSON),
+			filter.Tag,
+		)
+	}
+	if filter.HasBlockData != nil {
+		if *filter.HasBlockData {
+			db = db.Where("articles.block_data IS NOT NULL")
+		} else {
+			db = db.Where("articles.block_data IS NULL")
+		}
+	}
+	if filter.RawJSON != nil {
+		var err error
+		db, err = filter.RawJSON.apply(db)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return db, nil
+}
+
+// ListArticles returns a page of articles matching the filter, ordered by most
+// recently indexed first. Pagination is keyset based on (indexed_timestamp, id),
+// pass the returned NextCursor in the PageRequest to fetch the next page.
+func (p *GormPGPersister) ListArticles(filter *ArticleFilter, page *PageRequest) (*ArticleListing, error) {
+	return listArticles(p.DB.Model(&Gorm{}), filter, page)
+}
+
+// listArticles returns a page of the articles matched by the query on db and the filter
+func listArticles(db *gorm.DB, filter *ArticleFilter, page *PageRequest) (*ArticleListing, error) {
+	limit := pageLimit(page)
+
+	db, err := applyArticleFilter(db, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if page != nil && page.Cursor != "" {
+		cursor, err := listcursor.Decode(page.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where(
+			"(articles.indexed_timestamp, articles.id) < (?, ?)",
+			cursor.IndexedTimestamp,
+			cursor.ID,
+		)
+	}
+
+	articleGorms := []Gorm{}
+	err = db.Order("articles.indexed_timestamp DESC, articles.id DESC").
+		Limit(limit + 1).
+		Find(&articleGorms).Error
+	if err != nil {
+		return nil, err
+	}
+
+	listing := &ArticleListing{}
+	if len(articleGorms) > limit {
+		articleGorms = articleGorms[:limit]
+		last := articleGorms[limit-1]
+		listing.NextCursor = listcursor.Cursor{
+			IndexedTimestamp: last.IndexedTimestamp,
+			ID:               last.ID,
+		}.Encode()
+	}
+
+	listing.Articles, err = convertArticleGorms(articleGorms)
+	if err != nil {
+		return nil, err
+	}
+
+	return listing, nil
+}
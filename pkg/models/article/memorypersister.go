@@ -0,0 +1,353 @@
+package article
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/jinzhu/gorm"
+	"github.com/joincivil/go-common-priv/pkg/models/internal/listcursor"
+	carticle "github.com/joincivil/go-common/pkg/article"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrRawJSONQueryNotSupported indicates that the persister cannot evaluate raw json queries
+	ErrRawJSONQueryNotSupported = errors.New("raw json queries are not supported")
+)
+
+// MemoryPersister is an in-memory implementation of Persister for tests. Articles are
+// stored as gorm structs, so they are converted the same way as in GormPGPersister.
+// It is safe for concurrent use.
+type MemoryPersister struct {
+	// RequireContentHashMatch fails CreateArticle and UpdateArticle if the content hash
+	// does not match the raw json, as in GormPGPersister
+	RequireContentHashMatch bool
+
+	mu       sync.RWMutex
+	articles map[uint]*Gorm
+	lastID   uint
+}
+
+// NewMemoryPersister returns an empty MemoryPersister
+func NewMemoryPersister() *MemoryPersister {
+	return &MemoryPersister{articles: map[uint]*Gorm{}}
+}
+
+// ArticleByID finds an article by its ID. Returns gorm.ErrRecordNotFound if there is
+// no match, as in GormPGPersister.
+func (p *MemoryPersister) ArticleByID(articleID uint, opts ...LookupOption) (*carticle.Article, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	articleGorm, ok := p.articles[articleID]
+	if !ok || !isVisible(articleGorm, NewLookupOptions(opts...).IncludeDeleted) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return articleGorm.ConvertToArticle()
+}
+
+// ArticleByCanonicalURL finds the most recently created article with the given canonical URL.
+// Returns ErrArticleNotFound if there is no match.
+func (p *MemoryPersister) ArticleByCanonicalURL(canonicalURL string, opts ...LookupOption) (*carticle.Article, error) {
+	return p.articleByMetadata(func(metadata *carticle.Metadata) bool {
+		return metadata.CanonicalURL == canonicalURL
+	}, opts)
+}
+
+// ArticleByRevisionContentHash finds the most recently created article with the given
+// revision content hash. Returns ErrArticleNotFound if there is no match.
+func (p *MemoryPersister) ArticleByRevisionContentHash(contentHash string, opts ...LookupOption) (*carticle.Article, error) {
+	return p.articleByMetadata(func(metadata *carticle.Metadata) bool {
+		return metadata.RevisionContentHash == contentHash
+	}, opts)
+}
+
+func (p *MemoryPersister) articleByMetadata(match func(metadata *carticle.Metadata) bool,
+	opts []LookupOption) (*carticle.Article, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	includeDeleted := NewLookupOptions(opts...).IncludeDeleted
+	var found *carticle.Article
+	for _, articleGorm := range p.articles {
+		if !isVisible(articleGorm, includeDeleted) {
+			continue
+		}
+		article, err := articleGorm.ConvertToArticle()
+		if err != nil {
+			return nil, err
+		}
+		if match(&article.ArticleMetadata) && (found == nil || article.ID > found.ID) {
+			found = article
+		}
+	}
+
+	if found == nil {
+		return nil, ErrArticleNotFound
+	}
+	return found, nil
+}
+
+// CreateArticle saves an article and sets its ID. Block data is only saved by updates,
+// as in GormPGPersister.
+func (p *MemoryPersister) CreateArticle(article *carticle.Article) error {
+	if p.RequireContentHashMatch {
+		if err := VerifyContentHash(article); err != nil {
+			return err
+		}
+	}
+
+	articleGorm, err := newArticleGorm(article)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.lastID++
+	articleGorm.ID = p.lastID
+	articleGorm.CreatedAt = gorm.NowFunc()
+	articleGorm.UpdatedAt = articleGorm.CreatedAt
+	p.store(articleGorm)
+
+	article.ID = articleGorm.ID
+	return nil
+}
+
+// UpdateArticle saves updates to an article. The anchor state only changes through
+// transitions and is kept, as in GormPGPersister. Returns ErrArticleNotFound if the
+// article is soft deleted.
+func (p *MemoryPersister) UpdateArticle(article *carticle.Article) error {
+	if p.RequireContentHashMatch {
+		if err := VerifyContentHash(article); err != nil {
+			return err
+		}
+	}
+
+	articleGorm := &Gorm{}
+	if err := articleGorm.PopulateFromArticle(article); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if existing, ok := p.articles[articleGorm.ID]; ok && existing.DeletedAt != nil {
+		return ErrArticleNotFound
+	}
+	p.save(articleGorm)
+	return nil
+}
+
+// save stores the article like gorm Save, creating it if it has no ID or does not exist
+func (p *MemoryPersister) save(articleGorm *Gorm) {
+	now := gorm.NowFunc()
+	articleGorm.UpdatedAt = now
+	articleGorm.AnchorState = AnchorStatePending
+	if articleGorm.TxHash != "" {
+		articleGorm.AnchorState = AnchorStateConfirmed
+	}
+
+	if existing, ok := p.articles[articleGorm.ID]; ok && articleGorm.ID != 0 {
+		articleGorm.CreatedAt = existing.CreatedAt
+		articleGorm.DeletedAt = existing.DeletedAt
+		if articleGorm.TxHash == "" {
+			articleGorm.AnchorState = existing.AnchorState
+		}
+	} else {
+		if articleGorm.ID == 0 {
+			p.lastID++
+			articleGorm.ID = p.lastID
+		} else if articleGorm.ID > p.lastID {
+			p.lastID = articleGorm.ID
+		}
+		articleGorm.CreatedAt = now
+	}
+	p.store(articleGorm)
+}
+
+// store saves a copy of the article, so callers cannot change it afterwards
+func (p *MemoryPersister) store(articleGorm *Gorm) {
+	stored := *articleGorm
+	stored.RawJSON.RawMessage = append([]byte(nil), articleGorm.RawJSON.RawMessage...)
+	if len(articleGorm.RawJSON.RawMessage) == 0 {
+		stored.RawJSON.RawMessage = nil
+	}
+	p.articles[stored.ID] = &stored
+}
+
+// ListArticles returns a page of articles matching the filter, ordered by most recently
+// indexed first, as in GormPGPersister. Raw json queries are not supported.
+func (p *MemoryPersister) ListArticles(filter *ArticleFilter, page *PageRequest) (*ArticleListing, error) {
+	if filter == nil {
+		filter = &ArticleFilter{}
+	}
+	if filter.RawJSON != nil {
+		return nil, ErrRawJSONQueryNotSupported
+	}
+
+	var cursor *listcursor.Cursor
+	if page != nil && page.Cursor != "" {
+		var err error
+		cursor, err = listcursor.Decode(page.Cursor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p.mu.RLock()
+	articles := []*carticle.Article{}
+	for _, articleGorm := range p.articles {
+		if !isVisible(articleGorm, filter.IncludeDeleted) {
+			continue
+		}
+		article, err := articleGorm.ConvertToArticle()
+		if err != nil {
+			p.mu.RUnlock()
+			return nil, err
+		}
+		if matchesFilter(article, articleGorm, filter) && isBeforeCursor(article, cursor) {
+			articles = append(articles, article)
+		}
+	}
+	p.mu.RUnlock()
+
+	sort.Slice(articles, func(i, j int) bool {
+		return isListedBefore(articles[i], articles[j])
+	})
+
+	limit := pageLimit(page)
+	listing := &ArticleListing{}
+	if len(articles) > limit {
+		articles = articles[:limit]
+		last := articles[limit-1]
+		listing.NextCursor = listcursor.Cursor{
+			IndexedTimestamp: last.IndexedTimestamp,
+			ID:               last.ID,
+		}.Encode()
+	}
+
+	listing.Articles = make([]carticle.Article, len(articles))
+	for i, article := range articles {
+		listing.Articles[i] = *article
+	}
+	return listing, nil
+}
+
+// IterateArticles returns an Iterator over all the articles matching the filter, in the
+// order of ListArticles
+func (p *MemoryPersister) IterateArticles(filter *ArticleFilter, batchSize int) Iterator {
+	return NewListIterator(func(page *PageRequest) (*ArticleListing, error) {
+		return p.ListArticles(filter, page)
+	}, batchSize)
+}
+
+// DeleteArticle soft deletes the article with the given ID.
+// Returns ErrArticleNotFound if there is no article that is not already deleted.
+func (p *MemoryPersister) DeleteArticle(articleID uint) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	articleGorm, ok := p.articles[articleID]
+	if !ok || articleGorm.DeletedAt != nil {
+		return ErrArticleNotFound
+	}
+	deletedAt := gorm.NowFunc()
+	articleGorm.DeletedAt = &deletedAt
+	return nil
+}
+
+// RestoreArticle restores a soft deleted article with the given ID.
+// Returns ErrArticleNotFound if there is no deleted article.
+func (p *MemoryPersister) RestoreArticle(articleID uint) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	articleGorm, ok := p.articles[articleID]
+	if !ok || articleGorm.DeletedAt == nil {
+		return ErrArticleNotFound
+	}
+	articleGorm.DeletedAt = nil
+	return nil
+}
+
+// PurgeArticle permanently deletes the article with the given ID, whether or not it was
+// soft deleted. Returns ErrArticleNotFound if there is no article.
+func (p *MemoryPersister) PurgeArticle(articleID uint) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.articles[articleID]; !ok {
+		return ErrArticleNotFound
+	}
+	delete(p.articles, articleID)
+	return nil
+}
+
+// SaveArticleGorm stores the article like a gorm Save on the articles table, creating it
+// if it has no ID, and sets the ID. It is used to add articles through other models, such
+// as newsroom articles.
+func (p *MemoryPersister) SaveArticleGorm(articleGorm *Gorm) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.save(articleGorm)
+}
+
+func isVisible(articleGorm *Gorm, includeDeleted bool) bool {
+	return includeDeleted || articleGorm.DeletedAt == nil
+}
+
+// matchesFilter mirrors the conditions of applyArticleFilter
+func matchesFilter(article *carticle.Article, articleGorm *Gorm, filter *ArticleFilter) bool {
+	if filter.NewsroomAddress != "" &&
+		article.NewsroomAddress != NormalizeNewsroomAddress(filter.NewsroomAddress) {
+		return false
+	}
+	if !filter.IndexedAfter.IsZero() && article.IndexedTimestamp.Before(filter.IndexedAfter) {
+		return false
+	}
+	if !filter.IndexedBefore.IsZero() && !article.IndexedTimestamp.Before(filter.IndexedBefore) {
+		return false
+	}
+	published := article.ArticleMetadata.OriginalPublishDate
+	if !filter.PublishedAfter.IsZero() && published.Before(filter.PublishedAfter) {
+		return false
+	}
+	if !filter.PublishedBefore.IsZero() && !published.Before(filter.PublishedBefore) {
+		return false
+	}
+	if filter.Tag != "" && !hasTag(&article.ArticleMetadata, filter.Tag) {
+		return false
+	}
+	if filter.HasBlockData != nil && *filter.HasBlockData != (len(articleGorm.BlockData.RawMessage) != 0) {
+		return false
+	}
+	return true
+}
+
+func hasTag(metadata *carticle.Metadata, tag string) bool {
+	if metadata.PrimaryTag == tag {
+		return true
+	}
+	for _, t := range metadata.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// isListedBefore returns true if a comes before b in the (indexed_timestamp, id) DESC order
+func isListedBefore(a, b *carticle.Article) bool {
+	if !a.IndexedTimestamp.Equal(b.IndexedTimestamp) {
+		return a.IndexedTimestamp.After(b.IndexedTimestamp)
+	}
+	return a.ID > b.ID
+}
+
+func isBeforeCursor(article *carticle.Article, cursor *listcursor.Cursor) bool {
+	if cursor == nil {
+		return true
+	}
+	return isListedBefore(&carticle.Article{IndexedTimestamp: cursor.IndexedTimestamp, ID: cursor.ID}, article)
+}
@@ -0,0 +1,139 @@
+package article_test
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/joincivil/go-common-priv/pkg/models/article"
+	"github.com/joincivil/go-common-priv/pkg/models/testutils"
+	carticle "github.com/joincivil/go-common/pkg/article"
+)
+
+func TestMemoryPersister(t *testing.T) {
+	persister := article.NewMemoryPersister()
+	testFunc(persister)
+
+	newsroomAddr := "0x7c722B8AC728aDd7780a66017e8daDBa530EE261"
+	now := time.Now()
+
+	first := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Title:               "first",
+			CanonicalURL:        "https://newstuff.bz/memoryarticle",
+			RevisionContentHash: "0xfirst",
+			Tags:                []string{"news"},
+		},
+		NewsroomAddress:  newsroomAddr,
+		IndexedTimestamp: now,
+		RawJSON:          []byte(`{"title": "first"}`),
+	}
+	second := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Title:        "second",
+			CanonicalURL: "https://newstuff.bz/memoryarticle",
+			PrimaryTag:   "news",
+		},
+		NewsroomAddress:  newsroomAddr,
+		IndexedTimestamp: now,
+	}
+	third := &carticle.Article{
+		ArticleMetadata:  carticle.Metadata{Title: "third"},
+		NewsroomAddress:  "0x3e39fa983abcd349d95aed608e798817397cf0d1",
+		IndexedTimestamp: now.Add(time.Second),
+	}
+	for _, a := range []*carticle.Article{first, second, third} {
+		if err := persister.CreateArticle(a); err != nil {
+			t.Errorf("should have created article: err: %v", err)
+		}
+	}
+	if first.ID == 0 || second.ID <= first.ID {
+		t.Errorf("should have assigned increasing ids")
+	}
+
+	first.RawJSON[2] = 'X'
+	found, err := persister.ArticleByID(first.ID)
+	if err != nil {
+		t.Fatalf("should have found the article: err: %v", err)
+	}
+	if string(found.RawJSON) != `{"title": "first"}` {
+		t.Errorf("should have stored a copy of the raw json: %v", string(found.RawJSON))
+	}
+	if _, err := persister.ArticleByID(1000); err != gorm.ErrRecordNotFound {
+		t.Errorf("should have returned gorm.ErrRecordNotFound: err: %v", err)
+	}
+
+	found, err = persister.ArticleByCanonicalURL("https://newstuff.bz/memoryarticle")
+	if err != nil || found.ID != second.ID {
+		t.Errorf("should have found the most recent article by canonical url: err: %v", err)
+	}
+	if _, err := persister.ArticleByRevisionContentHash("0xnone"); err != article.ErrArticleNotFound {
+		t.Errorf("should have returned ErrArticleNotFound: err: %v", err)
+	}
+
+	listing, err := persister.ListArticles(&article.ArticleFilter{Tag: "news"}, &article.PageRequest{Limit: 1})
+	if err != nil {
+		t.Fatalf("should have listed the articles: err: %v", err)
+	}
+	if len(listing.Articles) != 1 || listing.Articles[0].ID != second.ID || listing.NextCursor == "" {
+		t.Errorf("should have listed the highest id first for the same timestamp")
+	}
+	listing, err = persister.ListArticles(
+		&article.ArticleFilter{Tag: "news"},
+		&article.PageRequest{Limit: 1, Cursor: listing.NextCursor},
+	)
+	if err != nil || len(listing.Articles) != 1 || listing.Articles[0].ID != first.ID || listing.NextCursor != "" {
+		t.Errorf("should have listed the last article on the next page: err: %v", err)
+	}
+
+	hasBlockData := true
+	listing, _ = persister.ListArticles(&article.ArticleFilter{HasBlockData: &hasBlockData}, nil)
+	if len(listing.Articles) != 0 {
+		t.Errorf("should not have saved block data on create")
+	}
+
+	first.BlockData = testutils.MakeFakeReceipt()
+	first.BlockData.BlockNumber = big.NewInt(10)
+	if err := persister.UpdateArticle(first); err != nil {
+		t.Errorf("should have updated the article: err: %v", err)
+	}
+	listing, _ = persister.ListArticles(&article.ArticleFilter{HasBlockData: &hasBlockData}, nil)
+	if len(listing.Articles) != 1 || listing.Articles[0].BlockData.BlockNumber.Uint64() != 10 {
+		t.Errorf("should have saved block data on update")
+	}
+
+	if err := persister.DeleteArticle(second.ID); err != nil {
+		t.Errorf("should have deleted the article: err: %v", err)
+	}
+	if err := persister.DeleteArticle(second.ID); err != article.ErrArticleNotFound {
+		t.Errorf("should not have deleted the article twice: err: %v", err)
+	}
+	if _, err := persister.ArticleByID(second.ID); err == nil {
+		t.Errorf("should not have found the deleted article")
+	}
+	if _, err := persister.ArticleByID(second.ID, article.IncludeDeleted()); err != nil {
+		t.Errorf("should have found the deleted article: err: %v", err)
+	}
+	if err := persister.RestoreArticle(second.ID); err != nil {
+		t.Errorf("should have restored the article: err: %v", err)
+	}
+	if err := persister.PurgeArticle(second.ID); err != nil {
+		t.Errorf("should have purged the article: err: %v", err)
+	}
+	if _, err := persister.ArticleByID(second.ID, article.IncludeDeleted()); err == nil {
+		t.Errorf("should not have found the purged article")
+	}
+
+	_, err = persister.ListArticles(&article.ArticleFilter{RawJSON: article.NewRawJSONQuery()}, nil)
+	if err != article.ErrRawJSONQueryNotSupported {
+		t.Errorf("should have returned ErrRawJSONQueryNotSupported: err: %v", err)
+	}
+}
+
+func TestMemoryPersisterSuite(t *testing.T) {
+	testutils.RunArticlePersisterSuite(t, func(t *testing.T) (article.Persister, func()) {
+		return article.NewMemoryPersister(), func() {}
+	})
+}
@@ -1,12 +1,104 @@
 package article
 
 import (
+	"time"
+
 	carticle "github.com/joincivil/go-common/pkg/article"
 )
 
 // Persister an interface for persisting articles
 type Persister interface {
-	ArticleByID(articleID uint) (*carticle.Article, error)
+	ArticleByID(articleID uint, opts ...LookupOption) (*carticle.Article, error)
+	ArticleByCanonicalURL(canonicalURL string, opts ...LookupOption) (*carticle.Article, error)
+	ArticleByRevisionContentHash(contentHash string, opts ...LookupOption) (*carticle.Article, error)
 	CreateArticle(article *carticle.Article) error
 	UpdateArticle(article *carticle.Article) error
+	ListArticles(filter *ArticleFilter, page *PageRequest) (*ArticleListing, error)
+	IterateArticles(filter *ArticleFilter, batchSize int) Iterator
+	DeleteArticle(articleID uint) error
+	RestoreArticle(articleID uint) error
+	PurgeArticle(articleID uint) error
+}
+
+// LookupOptions are the options for looking up a single article
+type LookupOptions struct {
+	// IncludeDeleted includes soft deleted articles in the lookup
+	IncludeDeleted bool
+}
+
+// LookupOption sets an option on an article lookup
+type LookupOption func(opts *LookupOptions)
+
+// IncludeDeleted is a LookupOption that includes soft deleted articles
+func IncludeDeleted() LookupOption {
+	return func(opts *LookupOptions) {
+		opts.IncludeDeleted = true
+	}
+}
+
+// NewLookupOptions returns the LookupOptions with the given options applied
+func NewLookupOptions(opts ...LookupOption) *LookupOptions {
+	lookupOpts := &LookupOptions{}
+	for _, opt := range opts {
+		opt(lookupOpts)
+	}
+	return lookupOpts
+}
+
+// RevisionPersister is an interface for retrieving earlier revisions of articles
+type RevisionPersister interface {
+	ArticleRevisions(articleID uint) ([]carticle.Article, error)
+	ArticleAtRevision(articleID uint, contentHash string) (*carticle.Article, error)
+}
+
+// ArticleFilter narrows down the articles returned by ListArticles. Zero values
+// are ignored, so an empty filter matches all articles.
+type ArticleFilter struct {
+	// NewsroomAddress matches the newsroom address of the article
+	NewsroomAddress string
+	// IndexedAfter matches articles indexed at or after the given time
+	IndexedAfter time.Time
+	// IndexedBefore matches articles indexed before the given time
+	IndexedBefore time.Time
+	// PublishedAfter matches articles originally published at or after the given time
+	PublishedAfter time.Time
+	// PublishedBefore matches articles originally published before the given time
+	PublishedBefore time.Time
+	// Tag matches articles with the given tag or primary tag
+	Tag string
+	// HasBlockData matches articles with or without block data if set
+	HasBlockData *bool
+	// RawJSON matches articles whose raw json matches the query
+	RawJSON *RawJSONQuery
+	// IncludeDeleted includes soft deleted articles
+	IncludeDeleted bool
+}
+
+// PageRequest specifies which page of results to return. An empty Cursor
+// requests the first page.
+type PageRequest struct {
+	Cursor string
+	Limit  int
+}
+
+// ArticleListing is a page of articles. NextCursor is empty if there are no
+// more pages.
+type ArticleListing struct {
+	Articles   []carticle.Article
+	NextCursor string
+}
+
+// SearchResult is an article matching a search with its relevance rank and a snippet
+// of the matching text. The snippet is HTML escaped text with the matches in <b> tags.
+type SearchResult struct {
+	Article carticle.Article
+	Rank    float64
+	Snippet string
+}
+
+// SearchResults is a page of search results ordered by rank. NextCursor is empty if
+// there are no more pages.
+type SearchResults struct {
+	Results    []SearchResult
+	NextCursor string
 }
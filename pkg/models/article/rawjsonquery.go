@@ -0,0 +1,176 @@
+package article
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrPathQueryNotSupported indicates that the postgres server is older than 12, which
+	// added the SQL/JSON path functions used by PathExists
+	ErrPathQueryNotSupported = errors.New("raw json path queries require postgres 12 or later")
+)
+
+// pathQueryMinServerVersion is the server_version_num of postgres 12
+const pathQueryMinServerVersion = 120000
+
+// RawJSONQuery builds conditions on the raw json of articles. Conditions are ANDed
+// together. Containment conditions compile to raw_json @> and use the GIN index from
+// ArticleRawJSONIndex, path conditions compile to jsonb_path_exists. Set it as the
+// RawJSON field of an ArticleFilter to use it with ListArticles.
+//
+// Errors from building the query, such as values that cannot be marshalled, are
+// returned when the query is used.
+type RawJSONQuery struct {
+	conditions []string
+	args       []interface{}
+	hasPath    bool
+	err        error
+}
+
+// NewRawJSONQuery returns an empty RawJSONQuery, which matches all articles
+func NewRawJSONQuery() *RawJSONQuery {
+	return &RawJSONQuery{}
+}
+
+// Contains matches articles whose raw json contains the given document, for example
+// map[string]interface{}{"title": "some title"}.
+func (q *RawJSONQuery) Contains(doc interface{}) *RawJSONQuery {
+	docJSON, err := json.Marshal(doc)
+	if err != nil {
+		q.setErr(errors.Wrap(err, "error marshalling raw json query document"))
+		return q
+	}
+	return q.addCondition("articles.raw_json @> ?::jsonb", string(docJSON))
+}
+
+// HasValue matches articles with the value at the path of object keys
+func (q *RawJSONQuery) HasValue(path []string, value interface{}) *RawJSONQuery {
+	if len(path) == 0 {
+		q.setErr(errors.New("raw json query path cannot be empty"))
+		return q
+	}
+	return q.Contains(nestUnderPath(path, value))
+}
+
+// ArrayContains matches articles with an array at the path of object keys that contains
+// all of the given elements. Object elements match if they contain the given fields, so
+// {"name": "X", "role": "editor"} matches a contributor named X with the role editor
+// regardless of their other fields.
+func (q *RawJSONQuery) ArrayContains(path []string, elems ...interface{}) *RawJSONQuery {
+	if len(path) == 0 {
+		q.setErr(errors.New("raw json query path cannot be empty"))
+		return q
+	}
+	if elems == nil {
+		elems = []interface{}{}
+	}
+	return q.Contains(nestUnderPath(path, elems))
+}
+
+// PathExists matches articles for which the SQL/JSON path expression returns any item,
+// for example `$.contributors[*] ? (@.role == $role)`. vars are the values of the
+// variables in the expression and can be nil. Path conditions cannot use the GIN index,
+// so combine them with a containment condition on large tables. Requires postgres 12 or
+// later, queries using it return ErrPathQueryNotSupported on older servers.
+func (q *RawJSONQuery) PathExists(jsonPath string, vars map[string]interface{}) *RawJSONQuery {
+	if vars == nil {
+		vars = map[string]interface{}{}
+	}
+	varsJSON, err := json.Marshal(vars)
+	if err != nil {
+		q.setErr(errors.Wrap(err, "error marshalling raw json query vars"))
+		return q
+	}
+	q.hasPath = true
+	return q.addCondition(
+		"jsonb_path_exists(articles.raw_json, ?::jsonpath, ?::jsonb)",
+		jsonPath,
+		string(varsJSON),
+	)
+}
+
+// Or matches articles that match any of the given queries
+func (q *RawJSONQuery) Or(queries ...*RawJSONQuery) *RawJSONQuery {
+	conditions := make([]string, 0, len(queries))
+	args := []interface{}{}
+	for _, sub := range queries {
+		sql, subArgs, err := sub.SQL()
+		if err != nil {
+			q.setErr(err)
+			return q
+		}
+		conditions = append(conditions, sql)
+		args = append(args, subArgs...)
+		q.hasPath = q.hasPath || sub.hasPath
+	}
+	if len(conditions) == 0 {
+		return q
+	}
+	return q.addCondition("("+strings.Join(conditions, " OR ")+")", args...)
+}
+
+// SQL returns the condition and its arguments with ? placeholders, or an error
+// if the query could not be built
+func (q *RawJSONQuery) SQL() (string, []interface{}, error) {
+	if q.err != nil {
+		return "", nil, q.err
+	}
+	if len(q.conditions) == 0 {
+		return "TRUE", nil, nil
+	}
+	return "(" + strings.Join(q.conditions, " AND ") + ")", q.args, nil
+}
+
+func (q *RawJSONQuery) apply(db *gorm.DB) (*gorm.DB, error) {
+	sql, args, err := q.SQL()
+	if err != nil {
+		return nil, err
+	}
+	if len(q.conditions) == 0 {
+		return db, nil
+	}
+	if q.hasPath {
+		if err := checkPathQuerySupport(db); err != nil {
+			return nil, err
+		}
+	}
+	return db.Where(sql, args...), nil
+}
+
+// checkPathQuerySupport returns ErrPathQueryNotSupported if the server does not have the
+// SQL/JSON path functions
+func checkPathQuerySupport(db *gorm.DB) error {
+	var version int
+	err := db.New().Raw("SELECT current_setting('server_version_num')::integer").Row().Scan(&version)
+	if err != nil {
+		return errors.Wrap(err, "error getting the server version")
+	}
+	if version < pathQueryMinServerVersion {
+		return errors.Wrapf(ErrPathQueryNotSupported, "server version %v", version)
+	}
+	return nil
+}
+
+func (q *RawJSONQuery) addCondition(sql string, args ...interface{}) *RawJSONQuery {
+	q.conditions = append(q.conditions, sql)
+	q.args = append(q.args, args...)
+	return q
+}
+
+func (q *RawJSONQuery) setErr(err error) {
+	if q.err == nil {
+		q.err = err
+	}
+}
+
+// nestUnderPath wraps the value in objects for each key of the path
+func nestUnderPath(path []string, value interface{}) interface{} {
+	for i := len(path) - 1; i >= 0; i-- {
+		value = map[string]interface{}{path[i]: value}
+	}
+	return value
+}
@@ -0,0 +1,125 @@
+package article_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/joincivil/go-common-priv/pkg/models/article"
+	"github.com/joincivil/go-common-priv/pkg/models/testutils"
+	carticle "github.com/joincivil/go-common/pkg/article"
+)
+
+func TestRawJSONQuerySQL(t *testing.T) {
+	sql, args, err := article.NewRawJSONQuery().
+		ArrayContains([]string{"contributors"}, map[string]string{"name": "X", "role": "editor"}).
+		PathExists("$.images[*] ? (@.w > $width)", map[string]interface{}{"width": 100}).
+		SQL()
+	if err != nil {
+		t.Errorf("should not have returned error: err: %v", err)
+	}
+
+	expectedSQL := "(articles.raw_json @> ?::jsonb AND " +
+		"jsonb_path_exists(articles.raw_json, ?::jsonpath, ?::jsonb))"
+	if sql != expectedSQL {
+		t.Errorf("unexpected sql: %v", sql)
+	}
+	if len(args) != 3 {
+		t.Fatalf("should have had 3 args: %v", args)
+	}
+	if args[0] != `{"contributors":[{"name":"X","role":"editor"}]}` {
+		t.Errorf("unexpected containment document: %v", args[0])
+	}
+	if args[2] != `{"width":100}` {
+		t.Errorf("unexpected path vars: %v", args[2])
+	}
+
+	sql, args, err = article.NewRawJSONQuery().Or(
+		article.NewRawJSONQuery().HasValue([]string{"meta", "lang"}, "en"),
+		article.NewRawJSONQuery().HasValue([]string{"meta", "lang"}, "fr"),
+	).SQL()
+	if err != nil {
+		t.Errorf("should not have returned error: err: %v", err)
+	}
+	if sql != "(((articles.raw_json @> ?::jsonb) OR (articles.raw_json @> ?::jsonb)))" {
+		t.Errorf("unexpected sql: %v", sql)
+	}
+	if len(args) != 2 || args[1] != `{"meta":{"lang":"fr"}}` {
+		t.Errorf("unexpected args: %v", args)
+	}
+
+	_, _, err = article.NewRawJSONQuery().HasValue(nil, "en").SQL()
+	if err == nil {
+		t.Errorf("should have returned error for an empty path")
+	}
+	_, _, err = article.NewRawJSONQuery().Contains(make(chan int)).SQL()
+	if err == nil {
+		t.Errorf("should have returned error for a document that cannot be marshalled")
+	}
+}
+
+func TestListArticlesRawJSONQuery(t *testing.T) {
+	creds := testutils.GetTestDBConnection()
+	pg, err := article.NewGormPGPersister(creds.Host, creds.Port, creds.User, creds.Password, creds.Dbname)
+
+	if err != nil {
+		fmt.Println(err)
+		t.Errorf("threw an error making the persister")
+	}
+
+	testutils.MigrateModels(pg.DB) // nolint: errcheck
+
+	defer pg.DB.Close()
+
+	cleaner := testutils.DeleteCreatedEntities(pg.DB)
+	defer cleaner()
+
+	newsroomAddr := "0x7c722B8AC728aDd7780a66017e8daDBa530EE261"
+	edited := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{CanonicalURL: "https://newstuff.bz/edited"},
+		NewsroomAddress: newsroomAddr,
+		RawJSON: []byte(`{"contributors": [
+			{"name": "Alice", "role": "author"},
+			{"name": "Bob", "role": "editor"}
+		]}`),
+	}
+	authored := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{CanonicalURL: "https://newstuff.bz/authored"},
+		NewsroomAddress: newsroomAddr,
+		RawJSON: []byte(`{"contributors": [
+			{"name": "Bob", "role": "author"}
+		]}`),
+	}
+	for _, a := range []*carticle.Article{edited, authored} {
+		if err := pg.CreateArticle(a); err != nil {
+			t.Errorf("should have created article: err: %v", err)
+		}
+	}
+
+	query := article.NewRawJSONQuery().
+		ArrayContains([]string{"contributors"}, map[string]string{"name": "Bob", "role": "editor"})
+	listing, err := pg.ListArticles(&article.ArticleFilter{
+		NewsroomAddress: newsroomAddr,
+		RawJSON:         query,
+	}, nil)
+	if err != nil {
+		t.Errorf("should not have returned error listing: err: %v", err)
+	}
+	if len(listing.Articles) != 1 || listing.Articles[0].ID != edited.ID {
+		t.Errorf("should have only listed the article Bob edited: %v", len(listing.Articles))
+	}
+
+	query = article.NewRawJSONQuery().PathExists(
+		"$.contributors[*] ? (@.name == $name && @.role == \"author\")",
+		map[string]interface{}{"name": "Bob"},
+	)
+	listing, err = pg.ListArticles(&article.ArticleFilter{
+		NewsroomAddress: newsroomAddr,
+		RawJSON:         query,
+	}, nil)
+	if err != nil {
+		t.Errorf("should not have returned error listing: err: %v", err)
+	}
+	if len(listing.Articles) != 1 || listing.Articles[0].ID != authored.ID {
+		t.Errorf("should have only listed the article Bob authored: %v", len(listing.Articles))
+	}
+}
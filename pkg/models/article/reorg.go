@@ -0,0 +1,152 @@
+package article
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/jinzhu/gorm"
+	"github.com/jinzhu/gorm/dialects/postgres"
+	carticle "github.com/joincivil/go-common/pkg/article"
+	"github.com/pkg/errors"
+)
+
+const (
+	// BlockDataRemovedReorg is the history reason for block data removed by a chain reorg
+	BlockDataRemovedReorg = "reorg"
+)
+
+// BlockDataHistoryGorm is the schema for block data that has been removed from an article
+type BlockDataHistoryGorm struct {
+	gorm.Model
+	ArticleID   uint `gorm:"index;not null"`
+	BlockData   postgres.Jsonb
+	TxHash      string
+	BlockNumber uint64
+	BlockHash   string
+	Reason      string
+}
+
+// TableName sets the name of the corresponding table in the db
+func (BlockDataHistoryGorm) TableName() string {
+	return "article_block_data_history"
+}
+
+// BlockDataHistory is block data that was removed from an article
+type BlockDataHistory struct {
+	ArticleID   uint
+	BlockData   ethTypes.Receipt
+	Reason      string
+	RemovedAt   time.Time
+	BlockHash   string
+	BlockNumber uint64
+}
+
+// UnanchorReorgedArticles takes the canonical block hash for each block height and marks
+// the confirmed articles anchored at those heights in a different block as unanchored. The
+// block data of those articles is moved to the block data history. Articles in other anchor
+// states are left as they are. Returns the IDs of the articles that were unanchored.
+func (p *GormPGPersister) UnanchorReorgedArticles(canonicalHashes map[uint64]ethCommon.Hash) ([]uint, error) {
+	blockNumbers := make([]uint64, 0, len(canonicalHashes))
+	for blockNumber := range canonicalHashes {
+		blockNumbers = append(blockNumbers, blockNumber)
+	}
+	sort.Slice(blockNumbers, func(i, j int) bool { return blockNumbers[i] < blockNumbers[j] })
+
+	unanchoredIDs := []uint{}
+	err := withTransaction(p.DB, func(tx *gorm.DB) error {
+		for _, blockNumber := range blockNumbers {
+			blockHash := canonicalHashes[blockNumber]
+			articleGorms := []Gorm{}
+			err := tx.Unscoped().
+				Set("gorm:query_option", "FOR UPDATE").
+				Where(
+					"anchor_state = ? AND tx_hash <> '' AND block_number = ? AND block_hash <> '' AND block_hash <> ?",
+					AnchorStateConfirmed,
+					blockNumber,
+					blockHash.Hex(),
+				).
+				Find(&articleGorms).Error
+			if err != nil {
+				return errors.Wrap(err, "error finding reorged articles")
+			}
+
+			for i := range articleGorms {
+				if err := unanchorArticle(tx, &articleGorms[i], BlockDataRemovedReorg); err != nil {
+					return err
+				}
+				unanchoredIDs = append(unanchoredIDs, articleGorms[i].ID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return unanchoredIDs, nil
+}
+
+// unanchorArticle moves the block data of the article into the history and clears it
+func unanchorArticle(tx *gorm.DB, articleGorm *Gorm, reason string) error {
+	history := &BlockDataHistoryGorm{
+		ArticleID:   articleGorm.ID,
+		BlockData:   articleGorm.BlockData,
+		TxHash:      articleGorm.TxHash,
+		BlockNumber: articleGorm.BlockNumber,
+		BlockHash:   articleGorm.BlockHash,
+		Reason:      reason,
+	}
+	if err := tx.Create(history).Error; err != nil {
+		return errors.Wrap(err, "error saving block data history")
+	}
+
+	if err := articleGorm.TransitionTo(AnchorStateUnanchored); err != nil {
+		return err
+	}
+
+	err := tx.Unscoped().Model(articleGorm).Updates(map[string]interface{}{
+		"block_data":   nil,
+		"tx_hash":      "",
+		"block_number": 0,
+		"block_hash":   "",
+		"tx_status":    0,
+		"anchor_state": articleGorm.AnchorState,
+	}).Error
+	return errors.Wrap(err, "error clearing block data")
+}
+
+// ArticlesNeedingReanchor returns the articles that were unanchored and have not been
+// anchored again. Filters by newsroom if newsroomAddress is not empty.
+func (p *GormPGPersister) ArticlesNeedingReanchor(newsroomAddress string) ([]carticle.Article, error) {
+	return p.ArticlesInState(newsroomAddress, AnchorStateUnanchored)
+}
+
+// ArticleBlockDataHistory returns the block data removed from the article, most recent first
+func (p *GormPGPersister) ArticleBlockDataHistory(articleID uint) ([]BlockDataHistory, error) {
+	historyGorms := []BlockDataHistoryGorm{}
+	err := p.DB.Where("article_id = ?", articleID).
+		Order("id DESC").
+		Find(&historyGorms).Error
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]BlockDataHistory, len(historyGorms))
+	for i, h := range historyGorms {
+		history[i] = BlockDataHistory{
+			ArticleID:   h.ArticleID,
+			Reason:      h.Reason,
+			RemovedAt:   h.CreatedAt,
+			BlockHash:   h.BlockHash,
+			BlockNumber: h.BlockNumber,
+		}
+		if err := json.Unmarshal(h.BlockData.RawMessage, &history[i].BlockData); err != nil {
+			return nil, errors.Wrap(err, "error unmarshalling block data")
+		}
+	}
+
+	return history, nil
+}
@@ -0,0 +1,211 @@
+package article_test
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/joincivil/go-common-priv/pkg/models/article"
+	"github.com/joincivil/go-common-priv/pkg/models/newsroom"
+	"github.com/joincivil/go-common-priv/pkg/models/testutils"
+	carticle "github.com/joincivil/go-common/pkg/article"
+)
+
+func TestUnanchorReorgedArticles(t *testing.T) {
+	creds := testutils.GetTestDBConnection()
+	pg, err := article.NewGormPGPersister(creds.Host, creds.Port, creds.User, creds.Password, creds.Dbname)
+
+	if err != nil {
+		fmt.Println(err)
+		t.Errorf("threw an error making the persister")
+	}
+
+	testutils.MigrateModels(pg.DB) // nolint: errcheck
+
+	defer pg.DB.Close()
+
+	cleaner := testutils.DeleteCreatedEntities(pg.DB)
+	defer cleaner()
+
+	newsroomAddr := "0x8c722B8AC728aDd7780a66017e8daDBa530EE261"
+	orphanedBlock := ethCommon.HexToHash("0xbad")
+	canonicalBlock := ethCommon.HexToHash("0x900d")
+
+	reorged := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Title:        "reorged stuff",
+			CanonicalURL: "https://newstuff.bz/reorgedarticle",
+		},
+		NewsroomAddress: newsroomAddr,
+	}
+	canonical := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Title:        "canonical stuff",
+			CanonicalURL: "https://newstuff.bz/canonicalarticle",
+		},
+		NewsroomAddress: newsroomAddr,
+	}
+
+	for _, a := range []*carticle.Article{reorged, canonical} {
+		if err := pg.CreateArticle(a); err != nil {
+			t.Fatalf("should have created article: err: %v", err)
+		}
+	}
+
+	reorged.BlockData = testutils.MakeFakeReceipt()
+	reorged.BlockData.BlockNumber = big.NewInt(500)
+	reorged.BlockData.BlockHash = orphanedBlock
+	if err := pg.UpdateArticle(reorged); err != nil {
+		t.Errorf("should have updated article: err: %v", err)
+	}
+
+	canonical.BlockData = testutils.MakeFakeReceipt()
+	canonical.BlockData.TxHash = ethCommon.HexToHash("0xc0ffee")
+	canonical.BlockData.BlockNumber = big.NewInt(501)
+	canonical.BlockData.BlockHash = canonicalBlock
+	if err := pg.UpdateArticle(canonical); err != nil {
+		t.Errorf("should have updated article: err: %v", err)
+	}
+
+	// An article with block data at the reorged height that is not confirmed is skipped
+	failed := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Title:        "failed stuff",
+			CanonicalURL: "https://newstuff.bz/failedarticle",
+		},
+		NewsroomAddress: newsroomAddr,
+		BlockData:       testutils.MakeFakeReceipt(),
+	}
+	failed.BlockData.TxHash = ethCommon.HexToHash("0xfa11ed")
+	failed.BlockData.BlockNumber = big.NewInt(500)
+	failed.BlockData.BlockHash = orphanedBlock
+	if err := pg.CreateArticle(failed); err != nil {
+		t.Fatalf("should have created article: err: %v", err)
+	}
+	err = pg.DB.Model(&article.Gorm{}).Where("id = ?", failed.ID).
+		Update("anchor_state", article.AnchorStateFailed).Error
+	if err != nil {
+		t.Fatalf("should have set the anchor state: err: %v", err)
+	}
+
+	ids, err := pg.UnanchorReorgedArticles(map[uint64]ethCommon.Hash{
+		500: canonicalBlock,
+		501: canonicalBlock,
+	})
+	if err != nil {
+		t.Fatalf("should have handled the reorg: err: %v", err)
+	}
+	if len(ids) != 1 || ids[0] != reorged.ID {
+		t.Errorf("should have only unanchored the reorged article: %v", ids)
+	}
+
+	found, err := pg.ArticleByID(reorged.ID)
+	if err != nil {
+		t.Errorf("threw an error looking up the article: err: %v", err)
+	} else if found.BlockData.TxHash != (ethCommon.Hash{}) {
+		t.Errorf("should have removed the block data of the reorged article")
+	}
+
+	found, err = pg.ArticleByID(canonical.ID)
+	if err != nil {
+		t.Errorf("threw an error looking up the article: err: %v", err)
+	} else if found.BlockData.TxHash == (ethCommon.Hash{}) {
+		t.Errorf("should have kept the block data of the canonical article")
+	}
+
+	history, err := pg.ArticleBlockDataHistory(reorged.ID)
+	if err != nil {
+		t.Errorf("should have retrieved block data history: err: %v", err)
+	}
+	if len(history) != 1 {
+		t.Fatalf("should have moved the block data to the history: len: %v", len(history))
+	}
+	if history[0].Reason != article.BlockDataRemovedReorg {
+		t.Errorf("should have recorded the reorg as the reason")
+	}
+	if history[0].BlockData.TxHash.Hex() != testutils.FakeTxHash {
+		t.Errorf("should have kept the receipt in the history")
+	}
+
+	needing, err := pg.ArticlesNeedingReanchor(newsroomAddr)
+	if err != nil {
+		t.Errorf("should have retrieved articles needing reanchoring: err: %v", err)
+	}
+	if len(needing) != 1 || needing[0].ID != reorged.ID {
+		t.Errorf("should have returned the reorged article as needing reanchoring")
+	}
+
+	// Anchoring the article again confirms it
+	reorged.BlockData = testutils.MakeFakeReceipt()
+	reorged.BlockData.BlockNumber = big.NewInt(502)
+	reorged.BlockData.BlockHash = canonicalBlock
+	if err := pg.UpdateArticle(reorged); err != nil {
+		t.Errorf("should have updated article: err: %v", err)
+	}
+
+	needing, err = pg.ArticlesNeedingReanchor(newsroomAddr)
+	if err != nil {
+		t.Errorf("should have retrieved articles needing reanchoring: err: %v", err)
+	}
+	if len(needing) != 0 {
+		t.Errorf("should not need reanchoring after new block data")
+	}
+}
+
+func TestUnanchorReorgedNewsroomArticle(t *testing.T) {
+	creds := testutils.GetTestDBConnection()
+	pg, err := article.NewGormPGPersister(creds.Host, creds.Port, creds.User, creds.Password, creds.Dbname)
+
+	if err != nil {
+		fmt.Println(err)
+		t.Errorf("threw an error making the persister")
+	}
+
+	testutils.MigrateModels(pg.DB) // nolint: errcheck
+
+	defer pg.DB.Close()
+
+	cleaner := testutils.DeleteCreatedEntities(pg.DB)
+	defer cleaner()
+
+	newsroomPersister, _ := newsroom.NewGormPGPersisterWithDB(pg.DB)
+	nr := &newsroom.Newsroom{Name: "Reorged newsroom", Address: "0x4c722B8AC728aDd7780a66017e8daDBa530EE261"}
+	if err := newsroomPersister.CreateNewsroom(nr); err != nil {
+		t.Fatalf("should have created the newsroom: err: %v", err)
+	}
+
+	// Articles added with a receipt are confirmed, so they can be unanchored by a reorg
+	narticle := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Title:        "anchored on add",
+			CanonicalURL: "https://newstuff.bz/anchoredonadd",
+		},
+		BlockData: testutils.MakeFakeReceipt(),
+	}
+	narticle.BlockData.BlockNumber = big.NewInt(987654)
+	narticle.BlockData.BlockHash = ethCommon.HexToHash("0xbad")
+	if err := newsroomPersister.AddArticle(nr.ID, narticle); err != nil {
+		t.Fatalf("should have added the article: err: %v", err)
+	}
+
+	articleGorm := &article.Gorm{}
+	err = pg.DB.Where("article_metadata->>'CanonicalURL' = ?", "https://newstuff.bz/anchoredonadd").
+		First(articleGorm).Error
+	if err != nil {
+		t.Fatalf("should have found the added article: err: %v", err)
+	}
+	if articleGorm.AnchorState != article.AnchorStateConfirmed {
+		t.Errorf("should have confirmed the article added with a receipt: %v", articleGorm.AnchorState)
+	}
+
+	ids, err := pg.UnanchorReorgedArticles(map[uint64]ethCommon.Hash{
+		987654: ethCommon.HexToHash("0x900d"),
+	})
+	if err != nil {
+		t.Fatalf("should have unanchored the added article: err: %v", err)
+	}
+	if len(ids) != 1 || ids[0] != articleGorm.ID {
+		t.Errorf("should have unanchored the added article: %v", ids)
+	}
+}
@@ -0,0 +1,145 @@
+package article
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/jinzhu/gorm/dialects/postgres"
+	gormutils "github.com/joincivil/go-common-priv/pkg/utils/gorm"
+	carticle "github.com/joincivil/go-common/pkg/article"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrRevisionNotFound indicates that no revision was found for the article and hash
+	ErrRevisionNotFound = errors.New("article revision not found")
+)
+
+// RevisionGorm is the article revision schema. A row is kept for each distinct
+// RevisionContentHash of an article.
+type RevisionGorm struct {
+	gorm.Model
+	ArticleID           uint   `gorm:"not null;unique_index:idx_article_revisions_article_id_hash"`
+	RevisionContentHash string `gorm:"not null;unique_index:idx_article_revisions_article_id_hash"`
+	RevisionContentURL  string
+	RevisionDate        time.Time
+	NewsroomAddress     string
+	IndexedTimestamp    time.Time
+	ArticleMetadata     postgres.Jsonb
+	BlockData           postgres.Jsonb
+	RawJSON             postgres.Jsonb `gorm:"column:raw_json"`
+	// RawJSONText is the raw json as it was saved, as with Gorm.RawJSONText
+	RawJSONText string `gorm:"column:raw_json_text;type:text"`
+}
+
+// TableName sets the name of the corresponding table in the db
+func (RevisionGorm) TableName() string {
+	return "article_revisions"
+}
+
+// ConvertToArticle returns the revision as the public article struct, with the ID
+// set to the ID of the article
+func (r *RevisionGorm) ConvertToArticle() (*carticle.Article, error) {
+	articleGorm := &Gorm{
+		BlockData:        r.BlockData,
+		ArticleMetadata:  r.ArticleMetadata,
+		NewsroomAddress:  r.NewsroomAddress,
+		IndexedTimestamp: r.IndexedTimestamp,
+		RawJSON:          r.RawJSON,
+		RawJSONText:      r.RawJSONText,
+	}
+	articleGorm.ID = r.ArticleID
+	return articleGorm.ConvertToArticle()
+}
+
+// ArticleRevisions returns every stored revision of the article, oldest first
+func (p *GormPGPersister) ArticleRevisions(articleID uint) ([]carticle.Article, error) {
+	revisionGorms := []RevisionGorm{}
+	err := p.DB.Where("article_id = ?", articleID).
+		Order("revision_date ASC, id ASC").
+		Find(&revisionGorms).Error
+	if err != nil {
+		return nil, err
+	}
+
+	articles := make([]carticle.Article, len(revisionGorms))
+	for i, r := range revisionGorms {
+		convertedArticle, err := r.ConvertToArticle()
+		if err != nil {
+			return nil, err
+		}
+		articles[i] = *convertedArticle
+	}
+
+	return articles, nil
+}
+
+// ArticleAtRevision returns the article as it was at the revision with the given content hash.
+// Returns ErrRevisionNotFound if there is no such revision.
+func (p *GormPGPersister) ArticleAtRevision(articleID uint, contentHash string) (*carticle.Article, error) {
+	revisionGorm := &RevisionGorm{}
+	err := p.DB.Where("article_id = ? AND revision_content_hash = ?", articleID, contentHash).
+		First(revisionGorm).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrRevisionNotFound
+		}
+		return nil, err
+	}
+
+	return revisionGorm.ConvertToArticle()
+}
+
+// saveRevision records the current state of the article as a revision if its content hash
+// has not been seen before. If the revision exists, block data is added to it if the
+// revision did not have any yet.
+func saveRevision(tx *gorm.DB, articleGorm *Gorm) error {
+	metadata := carticle.Metadata{}
+	if err := json.Unmarshal(articleGorm.ArticleMetadata.RawMessage, &metadata); err != nil {
+		return errors.Wrap(err, "error unmarshalling metadata")
+	}
+	if metadata.RevisionContentHash == "" {
+		return nil
+	}
+
+	existing := RevisionGorm{}
+	err := tx.Where(
+		"article_id = ? AND revision_content_hash = ?",
+		articleGorm.ID,
+		metadata.RevisionContentHash,
+	).First(&existing).Error
+
+	if gorm.IsRecordNotFoundError(err) {
+		revisionGorm := RevisionGorm{
+			ArticleID:           articleGorm.ID,
+			RevisionContentHash: metadata.RevisionContentHash,
+			RevisionContentURL:  metadata.RevisionContentURL,
+			RevisionDate:        metadata.RevisionDate,
+			NewsroomAddress:     articleGorm.NewsroomAddress,
+			IndexedTimestamp:    articleGorm.IndexedTimestamp,
+			ArticleMetadata:     articleGorm.ArticleMetadata,
+			BlockData:           articleGorm.BlockData,
+			RawJSON:             articleGorm.RawJSON,
+			RawJSONText:         articleGorm.RawJSONText,
+		}
+		return errors.Wrap(tx.Create(&revisionGorm).Error, "error creating revision")
+
+	} else if err != nil {
+		return errors.Wrap(err, "error finding revision")
+	}
+
+	if len(existing.BlockData.RawMessage) == 0 && len(articleGorm.BlockData.RawMessage) != 0 {
+		err = tx.Model(&existing).Update("block_data", articleGorm.BlockData).Error
+		return errors.Wrap(err, "error updating revision block data")
+	}
+
+	return nil
+}
+
+// withTransaction runs fn in a new transaction, or in a savepoint of the current
+// transaction if the db is already in one. It is not retried, as fn sets IDs on the
+// structs it saves.
+func withTransaction(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	return gormutils.WithTxOptions(db, &gormutils.TxOptions{MaxAttempts: 1}, fn)
+}
@@ -0,0 +1,153 @@
+package article_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/joincivil/go-common-priv/pkg/models/article"
+	"github.com/joincivil/go-common-priv/pkg/models/testutils"
+	carticle "github.com/joincivil/go-common/pkg/article"
+)
+
+func TestArticleRevisions(t *testing.T) {
+	creds := testutils.GetTestDBConnection()
+	pg, err := article.NewGormPGPersister(creds.Host, creds.Port, creds.User, creds.Password, creds.Dbname)
+
+	if err != nil {
+		fmt.Println(err)
+		t.Errorf("threw an error making the persister")
+	}
+
+	testutils.MigrateModels(pg.DB) // nolint: errcheck
+
+	defer pg.DB.Close()
+
+	cleaner := testutils.DeleteCreatedEntities(pg.DB)
+	defer cleaner()
+
+	now := time.Now()
+
+	narticle := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Title:               "first revision",
+			CanonicalURL:        "https://newstuff.bz/revisedarticle",
+			RevisionContentHash: "0xrevision1",
+			RevisionContentURL:  "https://newstuff.bz/revisions/1",
+			RevisionDate:        now,
+		},
+		NewsroomAddress: "0x8c722B8AC728aDd7780a66017e8daDBa530EE261",
+		RawJSON:         []byte(`{"title": "first revision"}`),
+	}
+
+	if err := pg.CreateArticle(narticle); err != nil {
+		t.Fatalf("should have created article: err: %v", err)
+	}
+
+	narticle.ArticleMetadata.Title = "second revision"
+	narticle.ArticleMetadata.RevisionContentHash = "0xrevision2"
+	narticle.ArticleMetadata.RevisionContentURL = "https://newstuff.bz/revisions/2"
+	narticle.ArticleMetadata.RevisionDate = now.Add(time.Minute)
+	narticle.RawJSON = []byte(`{"title": "second revision"}`)
+
+	if err := pg.UpdateArticle(narticle); err != nil {
+		t.Errorf("should have updated article: err: %v", err)
+	}
+
+	// Saving the same revision with a receipt should not add another revision
+	narticle.BlockData = testutils.MakeFakeReceipt()
+	if err := pg.UpdateArticle(narticle); err != nil {
+		t.Errorf("should have updated article: err: %v", err)
+	}
+
+	revisions, err := pg.ArticleRevisions(narticle.ID)
+	if err != nil {
+		t.Errorf("should have retrieved revisions: err: %v", err)
+	}
+	if len(revisions) != 2 {
+		t.Fatalf("should have retrieved 2 revisions: len: %v", len(revisions))
+	}
+	if revisions[0].ArticleMetadata.Title != "first revision" {
+		t.Errorf("should have returned the oldest revision first")
+	}
+	if revisions[1].BlockData.TxHash == (ethCommon.Hash{}) {
+		t.Errorf("should have added the block data to the latest revision")
+	}
+
+	atRevision, err := pg.ArticleAtRevision(narticle.ID, "0xrevision1")
+	if err != nil {
+		t.Errorf("should have retrieved the revision: err: %v", err)
+	}
+	if atRevision.ID != narticle.ID {
+		t.Errorf("revision should have the id of the article")
+	}
+	if string(atRevision.RawJSON) != `{"title": "first revision"}` {
+		t.Errorf("should have returned the raw json of the revision: %v", string(atRevision.RawJSON))
+	}
+
+	current, err := pg.ArticleByID(narticle.ID)
+	if err != nil {
+		t.Errorf("threw an error looking up the article: err: %v", err)
+	}
+	if current.ArticleMetadata.Title != "second revision" {
+		t.Errorf("article should have the latest revision")
+	}
+
+	_, err = pg.ArticleAtRevision(narticle.ID, "0xnotarevision")
+	if err != article.ErrRevisionNotFound {
+		t.Errorf("should have returned ErrRevisionNotFound: err: %v", err)
+	}
+}
+
+func TestArticleAtRevisionContentHash(t *testing.T) {
+	creds := testutils.GetTestDBConnection()
+	pg, err := article.NewGormPGPersister(creds.Host, creds.Port, creds.User, creds.Password, creds.Dbname)
+
+	if err != nil {
+		fmt.Println(err)
+		t.Errorf("threw an error making the persister")
+	}
+
+	testutils.MigrateModels(pg.DB) // nolint: errcheck
+
+	defer pg.DB.Close()
+
+	cleaner := testutils.DeleteCreatedEntities(pg.DB)
+	defer cleaner()
+
+	pg.RequireContentHashMatch = true
+
+	// jsonb reorders the keys, so the revision has to keep the text to match the hash
+	rawJSON := []byte(`{"title": "first revision", "body": "text"}`)
+	contentHash, err := article.ComputeContentHash(rawJSON)
+	if err != nil {
+		t.Fatalf("should have computed the content hash: err: %v", err)
+	}
+	narticle := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Title:               "first revision",
+			CanonicalURL:        "https://newstuff.bz/hashedrevision",
+			RevisionContentHash: contentHash,
+		},
+		NewsroomAddress: "0x8c722B8AC728aDd7780a66017e8daDBa530EE261",
+		RawJSON:         rawJSON,
+	}
+	if err := pg.CreateArticle(narticle); err != nil {
+		t.Fatalf("should have created article: err: %v", err)
+	}
+
+	narticle.RawJSON = []byte(`{"title": "second revision", "body": "text"}`)
+	narticle.ArticleMetadata.RevisionContentHash, _ = article.ComputeContentHash(narticle.RawJSON)
+	if err := pg.UpdateArticle(narticle); err != nil {
+		t.Fatalf("should have updated article: err: %v", err)
+	}
+
+	atRevision, err := pg.ArticleAtRevision(narticle.ID, contentHash)
+	if err != nil {
+		t.Fatalf("should have retrieved the revision: err: %v", err)
+	}
+	if err := article.VerifyContentHash(atRevision); err != nil {
+		t.Errorf("should have returned raw json matching the revision hash: err: %v", err)
+	}
+}
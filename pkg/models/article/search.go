@@ -0,0 +1,188 @@
+package article
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+
+	"github.com/joincivil/go-common-priv/pkg/models/migrations"
+)
+
+const (
+	searchConfig     = "english"
+	searchVectorName = "search_vector"
+	searchBodyFunc   = "articles_search_body"
+
+	// The searched parts of an article, %[1]s is the row prefix. The body is taken from the
+	// raw json by the function SetSearchBodyKeys replaces.
+	searchTitleSQL       = "coalesce(%[1]sarticle_metadata->>'Title', '')"
+	searchDescriptionSQL = "coalesce(%[1]sarticle_metadata->>'Description', '')"
+	searchBodySQL        = searchBodyFunc + "(%[1]sraw_json)"
+
+	// The text is escaped before the matches are highlighted, so snippets are safe to
+	// render as HTML
+	searchHeadlineOptions = "StartSel=<b>, StopSel=</b>, MaxWords=35, MinWords=15, MaxFragments=3"
+)
+
+// searchResultGorm is an article row with its search rank and snippet
+type searchResultGorm struct {
+	Gorm
+	SearchRank    float64
+	SearchSnippet string
+}
+
+// ArticleSearchIndex adds the search_vector column used by SearchArticles, its GIN index
+// and the trigger that keeps it up to date by applying the models migrations.
+//
+// Deprecated: use migrations.Up, which creates the column and backfills it.
+func (p *GormPGPersister) ArticleSearchIndex() error {
+	return migrations.Up(p.DB)
+}
+
+// searchBodyFuncSQL returns the statement creating the function that takes the body of an
+// article from its raw json, the value of the first of the keys that is set
+func searchBodyFuncSQL(keys []string) string {
+	values := make([]string, 0, len(keys)+1)
+	for _, key := range keys {
+		values = append(values, "doc->>"+pq.QuoteLiteral(key))
+	}
+	values = append(values, "''")
+	return fmt.Sprintf(
+		`CREATE OR REPLACE FUNCTION %s(doc jsonb) RETURNS text AS $body$
+			SELECT coalesce(%s)
+		$body$ LANGUAGE sql IMMUTABLE`,
+		searchBodyFunc,
+		strings.Join(values, ", "),
+	)
+}
+
+// SetSearchBodyKeys sets the top level keys of the raw json that are searched as the body
+// of an article. The value of the first key that is set is used. The keys are saved in the
+// db for all persisters, and the search vectors of the existing articles are rebuilt, which
+// rewrites every article. Until it is called, the "content" and "body" keys are searched.
+func (p *GormPGPersister) SetSearchBodyKeys(keys ...string) error {
+	return withTransaction(p.DB, func(tx *gorm.DB) error {
+		if err := tx.Exec(searchBodyFuncSQL(keys)).Error; err != nil {
+			return errors.Wrap(err, "error setting the search body keys")
+		}
+		// Setting the rows to themselves runs the trigger
+		err := tx.Exec(fmt.Sprintf("UPDATE %s SET id = id", Gorm{}.TableName())).Error
+		return errors.Wrap(err, "error rebuilding the search vectors")
+	})
+}
+
+// searchCursor is the keyset position of the last result on a page of search results
+type searchCursor struct {
+	Rank float64
+	ID   uint
+}
+
+// searchHeadlineQuery highlights the matches in the ranked page of articles from the
+// subquery, so only the returned articles get a headline. The text is escaped first.
+const searchHeadlineQuery = `
+SELECT articles.*, ranked.search_rank,
+	ts_headline('%[1]s',
+		replace(replace(replace(%[2]s, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+		search_query, '%[3]s') AS search_snippet
+FROM ? AS ranked
+JOIN articles ON articles.id = ranked.id,
+	plainto_tsquery('%[1]s', ?) search_query
+ORDER BY ranked.search_rank DESC, articles.id DESC`
+
+// SearchArticles returns the articles matching the search query and filter, ranked by
+// relevance with highlighted snippets. Requires the column from ArticleSearchIndex.
+func (p *GormPGPersister) SearchArticles(query string, filter *ArticleFilter,
+	page *PageRequest) (*SearchResults, error) {
+	limit := pageLimit(page)
+
+	db, err := applyArticleFilter(p.DB.Model(&Gorm{}), filter)
+	if err != nil {
+		return nil, err
+	}
+
+	rankSQL := fmt.Sprintf("ts_rank_cd(articles.%s, search_query)", searchVectorName)
+	if page != nil && page.Cursor != "" {
+		cursor, err := decodeSearchCursor(page.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where(
+			fmt.Sprintf("(%s, articles.id) < (?::real, ?)", rankSQL),
+			cursor.Rank,
+			cursor.ID,
+		)
+	}
+
+	ranked := db.Select("articles.id, "+rankSQL+" AS search_rank").
+		Joins(fmt.Sprintf(", plainto_tsquery('%s', ?) search_query", searchConfig), query).
+		Where(fmt.Sprintf("articles.%s @@ search_query", searchVectorName)).
+		Order("search_rank DESC, articles.id DESC").
+		Limit(limit + 1).
+		SubQuery()
+
+	document := fmt.Sprintf(
+		searchTitleSQL+" || ' ' || "+searchDescriptionSQL+" || ' ' || "+searchBodySQL,
+		"articles.",
+	)
+	headlineQuery := fmt.Sprintf(searchHeadlineQuery, searchConfig, document, searchHeadlineOptions)
+
+	resultGorms := []searchResultGorm{}
+	if err := p.DB.Raw(headlineQuery, ranked, query).Scan(&resultGorms).Error; err != nil {
+		return nil, err
+	}
+
+	results := &SearchResults{}
+	if len(resultGorms) > limit {
+		resultGorms = resultGorms[:limit]
+		last := resultGorms[limit-1]
+		results.NextCursor = encodeSearchCursor(searchCursor{Rank: last.SearchRank, ID: last.ID})
+	}
+
+	results.Results = make([]SearchResult, len(resultGorms))
+	for i, r := range resultGorms {
+		convertedArticle, err := r.Gorm.ConvertToArticle()
+		if err != nil {
+			return nil, err
+		}
+		results.Results[i] = SearchResult{
+			Article: *convertedArticle,
+			Rank:    r.SearchRank,
+			Snippet: r.SearchSnippet,
+		}
+	}
+
+	return results, nil
+}
+
+func encodeSearchCursor(c searchCursor) string {
+	s := strconv.FormatFloat(c.Rank, 'g', -1, 64) + ":" + strconv.FormatUint(uint64(c.ID), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+func decodeSearchCursor(cursor string) (*searchCursor, error) {
+	bys, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	parts := strings.Split(string(bys), ":")
+	if len(parts) != 2 {
+		return nil, ErrInvalidCursor
+	}
+
+	rank, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	id, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &searchCursor{Rank: rank, ID: uint(id)}, nil
+}
@@ -0,0 +1,179 @@
+package article_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joincivil/go-common-priv/pkg/models/article"
+	"github.com/joincivil/go-common-priv/pkg/models/testutils"
+	carticle "github.com/joincivil/go-common/pkg/article"
+)
+
+func TestSearchArticles(t *testing.T) {
+	creds := testutils.GetTestDBConnection()
+	pg, err := article.NewGormPGPersister(creds.Host, creds.Port, creds.User, creds.Password, creds.Dbname)
+
+	if err != nil {
+		fmt.Println(err)
+		t.Errorf("threw an error making the persister")
+	}
+
+	testutils.MigrateModels(pg.DB) // nolint: errcheck
+
+	defer pg.DB.Close()
+
+	cleaner := testutils.DeleteCreatedEntities(pg.DB)
+	defer cleaner()
+
+	if err := pg.ArticleSearchIndex(); err != nil {
+		t.Errorf("should have created the search index: err: %v", err)
+	}
+
+	newsroomAddr := "0x7c722B8AC728aDd7780a66017e8daDBa530EE261"
+	otherNewsroomAddr := "0x3e39fa983abcd349d95aed608e798817397cf0d1"
+
+	inTitle := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Title:        "Zeppelins return to the skies",
+			Description:  "A look at modern airships",
+			CanonicalURL: "https://newstuff.bz/zeppelintitle",
+		},
+		NewsroomAddress: newsroomAddr,
+		RawJSON:         []byte(`{"content": "<p>Lighter than air travel is back.</p>"}`),
+	}
+	inBody := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Title:        "Transport roundup",
+			Description:  "This week in transport",
+			CanonicalURL: "https://newstuff.bz/zeppelinbody",
+		},
+		NewsroomAddress: newsroomAddr,
+		RawJSON:         []byte(`{"content": "<p>Trains, buses and one zeppelin.</p>"}`),
+	}
+	otherNewsroom := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Title:        "Zeppelin sighted",
+			CanonicalURL: "https://othernews.bz/zeppelin",
+		},
+		NewsroomAddress: otherNewsroomAddr,
+		RawJSON:         []byte(`{"content": "<p>Nothing else to report.</p>"}`),
+	}
+	unrelated := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Title:        "Local bake sale",
+			CanonicalURL: "https://newstuff.bz/bakesale",
+		},
+		NewsroomAddress: newsroomAddr,
+		RawJSON:         []byte(`{"content": "<p>Cakes and pies.</p>"}`),
+	}
+
+	for _, a := range []*carticle.Article{inTitle, inBody, otherNewsroom, unrelated} {
+		if err := pg.CreateArticle(a); err != nil {
+			t.Errorf("should have created article: err: %v", err)
+		}
+	}
+
+	results, err := pg.SearchArticles("zeppelins", &article.ArticleFilter{NewsroomAddress: newsroomAddr}, nil)
+	if err != nil {
+		t.Errorf("should not have returned error searching: err: %v", err)
+	}
+	if len(results.Results) != 2 {
+		t.Fatalf("should have found the 2 matching articles in the newsroom: %v", len(results.Results))
+	}
+	if results.Results[0].Article.ID != inTitle.ID {
+		t.Errorf("should have ranked the title match first")
+	}
+	if results.Results[0].Rank <= results.Results[1].Rank {
+		t.Errorf("should have ranked the title match higher: %v", results.Results)
+	}
+	if !strings.Contains(results.Results[1].Snippet, "<b>zeppelin</b>") {
+		t.Errorf("should have highlighted the match in the snippet: %v", results.Results[1].Snippet)
+	}
+	if strings.Contains(results.Results[1].Snippet, "<p>") {
+		t.Errorf("should have escaped the text in the snippet: %v", results.Results[1].Snippet)
+	}
+
+	results, err = pg.SearchArticles("zeppelin", nil, &article.PageRequest{Limit: 2})
+	if err != nil {
+		t.Errorf("should not have returned error searching: err: %v", err)
+	}
+	if len(results.Results) != 2 || results.NextCursor == "" {
+		t.Fatalf("should have returned a full first page with a cursor")
+	}
+	results, err = pg.SearchArticles("zeppelin", nil, &article.PageRequest{Limit: 2, Cursor: results.NextCursor})
+	if err != nil {
+		t.Errorf("should not have returned error searching: err: %v", err)
+	}
+	if len(results.Results) != 1 || results.NextCursor != "" {
+		t.Errorf("should have returned the last result without a cursor: %v", results.Results)
+	}
+
+	results, err = pg.SearchArticles("zeppelin", &article.ArticleFilter{
+		IndexedAfter: time.Now().Add(time.Hour),
+	}, nil)
+	if err != nil {
+		t.Errorf("should not have returned error searching: err: %v", err)
+	}
+	if len(results.Results) != 0 {
+		t.Errorf("should not have found articles outside of the date range")
+	}
+
+	_, err = pg.SearchArticles("zeppelin", nil, &article.PageRequest{Cursor: "notacursor"})
+	if err != article.ErrInvalidCursor {
+		t.Errorf("should have returned ErrInvalidCursor: err: %v", err)
+	}
+}
+
+func TestSetSearchBodyKeys(t *testing.T) {
+	creds := testutils.GetTestDBConnection()
+	pg, err := article.NewGormPGPersister(creds.Host, creds.Port, creds.User, creds.Password, creds.Dbname)
+
+	if err != nil {
+		fmt.Println(err)
+		t.Errorf("threw an error making the persister")
+	}
+
+	testutils.MigrateModels(pg.DB) // nolint: errcheck
+
+	defer pg.DB.Close()
+
+	// The keys are saved in the db, so they are changed in a transaction that is rolled back
+	tx := pg.DB.Begin()
+	defer tx.Rollback()
+	txPersister, _ := article.NewGormPGPersisterWithDB(tx)
+
+	narticle := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Title:        "Weekend plans",
+			CanonicalURL: "https://newstuff.bz/dirigible",
+		},
+		NewsroomAddress: "0x7c722B8AC728aDd7780a66017e8daDBa530EE261",
+		RawJSON:         []byte(`{"text": "A dirigible over the <i>harbor</i>."}`),
+	}
+	if err := txPersister.CreateArticle(narticle); err != nil {
+		t.Fatalf("should have created article: err: %v", err)
+	}
+
+	results, err := txPersister.SearchArticles("dirigible", nil, nil)
+	if err != nil || len(results.Results) != 0 {
+		t.Errorf("should not have searched the text key yet: err: %v", err)
+	}
+
+	if err := txPersister.SetSearchBodyKeys("text"); err != nil {
+		t.Fatalf("should have set the search body keys: err: %v", err)
+	}
+
+	results, err = txPersister.SearchArticles("dirigible", nil, nil)
+	if err != nil {
+		t.Fatalf("should not have returned error searching: err: %v", err)
+	}
+	if len(results.Results) != 1 || results.Results[0].Article.ID != narticle.ID {
+		t.Fatalf("should have found the article by the text key: %v", results.Results)
+	}
+	snippet := results.Results[0].Snippet
+	if !strings.Contains(snippet, "<b>dirigible</b>") || !strings.Contains(snippet, "&lt;i&gt;harbor&lt;/i&gt;") {
+		t.Errorf("should have escaped the text and highlighted the match: %v", snippet)
+	}
+}
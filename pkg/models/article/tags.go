@@ -0,0 +1,163 @@
+package article
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jinzhu/gorm"
+	carticle "github.com/joincivil/go-common/pkg/article"
+	"github.com/pkg/errors"
+)
+
+const upsertTagQuery = `
+INSERT INTO tags (created_at, updated_at, slug, name)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (slug) DO UPDATE SET updated_at = tags.updated_at
+RETURNING id`
+
+// TagGorm is the schema for a normalized tag. Tags are unique by slug.
+type TagGorm struct {
+	ID        uint `gorm:"primary_key"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Slug      string `gorm:"not null;unique_index"`
+	// Name is the name the tag was first seen with
+	Name string
+}
+
+// TableName sets the name of the corresponding table in the db
+func (TagGorm) TableName() string {
+	return "tags"
+}
+
+// ArticleTagGorm links an article to a tag
+type ArticleTagGorm struct {
+	ID        uint `gorm:"primary_key"`
+	ArticleID uint `gorm:"not null;unique_index:idx_article_tags_article_id_tag_id"`
+	TagID     uint `gorm:"not null;unique_index:idx_article_tags_article_id_tag_id;index"`
+	// Primary is set if the tag is the primary tag of the article
+	Primary bool
+}
+
+// TableName sets the name of the corresponding table in the db
+func (ArticleTagGorm) TableName() string {
+	return "article_tags"
+}
+
+// TagCount is a tag with the number of articles it is on
+type TagCount struct {
+	Slug  string
+	Name  string
+	Count int
+}
+
+// NormalizeTag returns the slug of a tag. The tag is lowercased and runs of anything
+// other than letters and digits are replaced by a single dash, so "Climate Change"
+// and "climate-change" are the same tag.
+func NormalizeTag(tag string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(tag) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteRune('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// ArticlesByTag returns a page of articles with the given tag or primary tag, matched
+// by its normalized slug, ordered like ListArticles. The filter further narrows down
+// the articles and can be nil.
+func (p *GormPGPersister) ArticlesByTag(tag string, filter *ArticleFilter,
+	page *PageRequest) (*ArticleListing, error) {
+	db := p.DB.Model(&Gorm{}).Where(
+		"articles.id IN (SELECT article_tags.article_id FROM article_tags "+
+			"JOIN tags ON tags.id = article_tags.tag_id WHERE tags.slug = ?)",
+		NormalizeTag(tag),
+	)
+	return listArticles(db, filter, page)
+}
+
+// TagsForNewsroom returns the tags used on the articles of the newsroom with the number
+// of articles for each, most used first. Deleted articles are not counted.
+func (p *GormPGPersister) TagsForNewsroom(newsroomAddress string) ([]TagCount, error) {
+	return p.tagCounts(p.DB.Where("articles.newsroom_address = ?", NormalizeNewsroomAddress(newsroomAddress)), 0)
+}
+
+// TopTags returns up to limit of the most used tags on articles indexed since the
+// given time, with the number of articles for each. Deleted articles are not counted.
+func (p *GormPGPersister) TopTags(since time.Time, limit int) ([]TagCount, error) {
+	return p.tagCounts(p.DB.Where("articles.indexed_timestamp >= ?", since), limit)
+}
+
+func (p *GormPGPersister) tagCounts(db *gorm.DB, limit int) ([]TagCount, error) {
+	db = db.Table(TagGorm{}.TableName()).
+		Select("tags.slug, tags.name, COUNT(DISTINCT articles.id) AS count").
+		Joins("JOIN article_tags ON article_tags.tag_id = tags.id").
+		Joins("JOIN articles ON articles.id = article_tags.article_id").
+		Where("articles.deleted_at IS NULL").
+		Group("tags.slug, tags.name").
+		Order("count DESC, tags.slug ASC")
+	if limit > 0 {
+		db = db.Limit(limit)
+	}
+
+	counts := []TagCount{}
+	if err := db.Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// syncArticleTags replaces the tag links of the article with the tags in the metadata
+func syncArticleTags(tx *gorm.DB, articleID uint, metadata *carticle.Metadata) error {
+	err := tx.Where("article_id = ?", articleID).Delete(&ArticleTagGorm{}).Error
+	if err != nil {
+		return errors.Wrap(err, "error deleting article tags")
+	}
+
+	primarySlug := NormalizeTag(metadata.PrimaryTag)
+	tags := metadata.Tags
+	if metadata.PrimaryTag != "" {
+		tags = append([]string{metadata.PrimaryTag}, tags...)
+	}
+
+	linked := map[string]bool{}
+	for _, tag := range tags {
+		slug := NormalizeTag(tag)
+		if slug == "" || linked[slug] {
+			continue
+		}
+		linked[slug] = true
+
+		tagID, err := upsertTag(tx, slug, strings.TrimSpace(tag))
+		if err != nil {
+			return err
+		}
+
+		link := ArticleTagGorm{ArticleID: articleID, TagID: tagID, Primary: slug == primarySlug}
+		if err := tx.Create(&link).Error; err != nil {
+			return errors.Wrap(err, "error linking article tag")
+		}
+	}
+
+	return nil
+}
+
+// upsertTag returns the ID of the tag with the slug, creating it if it does not exist
+func upsertTag(tx *gorm.DB, slug string, name string) (uint, error) {
+	now := gorm.NowFunc()
+	var id uint
+	err := tx.Raw(upsertTagQuery, now, now, slug, name).Row().Scan(&id)
+	if err != nil {
+		return 0, errors.Wrap(err, "error saving tag")
+	}
+	return id, nil
+}
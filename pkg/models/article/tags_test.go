@@ -0,0 +1,135 @@
+package article_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/joincivil/go-common-priv/pkg/models/article"
+	"github.com/joincivil/go-common-priv/pkg/models/testutils"
+	carticle "github.com/joincivil/go-common/pkg/article"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	cases := map[string]string{
+		"Climate Change":     "climate-change",
+		"  climate--change ": "climate-change",
+		"C++ & Go!":          "c-go",
+		"Élections 2020":     "élections-2020",
+		"!!!":                "",
+	}
+	for tag, expected := range cases {
+		if slug := article.NormalizeTag(tag); slug != expected {
+			t.Errorf("should have normalized %q to %q: %q", tag, expected, slug)
+		}
+	}
+}
+
+func TestArticleTags(t *testing.T) {
+	creds := testutils.GetTestDBConnection()
+	pg, err := article.NewGormPGPersister(creds.Host, creds.Port, creds.User, creds.Password, creds.Dbname)
+
+	if err != nil {
+		fmt.Println(err)
+		t.Errorf("threw an error making the persister")
+	}
+
+	testutils.MigrateModels(pg.DB) // nolint: errcheck
+
+	defer pg.DB.Close()
+
+	cleaner := testutils.DeleteCreatedEntities(pg.DB)
+	defer cleaner()
+
+	// Tags are saved with raw sql so they are not tracked by the cleaner
+	slugs := []string{"testtag-politics", "testtag-climate-change", "testtag-weather"}
+	defer pg.DB.Where("slug IN (?)", slugs).Delete(&article.TagGorm{})
+
+	newsroomAddr := "0x7c722B8AC728aDd7780a66017e8daDBa530EE261"
+	first := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			CanonicalURL: "https://newstuff.bz/taggedarticle1",
+			PrimaryTag:   "TestTag Politics",
+			Tags:         []string{"testtag-politics", "TestTag Climate Change"},
+		},
+		NewsroomAddress:  newsroomAddr,
+		IndexedTimestamp: time.Now(),
+	}
+	second := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			CanonicalURL: "https://newstuff.bz/taggedarticle2",
+			Tags:         []string{"testtag climate change"},
+		},
+		NewsroomAddress:  newsroomAddr,
+		IndexedTimestamp: time.Now(),
+	}
+	for _, a := range []*carticle.Article{first, second} {
+		if err := pg.CreateArticle(a); err != nil {
+			t.Errorf("should have created article: err: %v", err)
+		}
+	}
+
+	listing, err := pg.ArticlesByTag("TESTTAG CLIMATE-CHANGE", nil, nil)
+	if err != nil {
+		t.Errorf("should not have returned error: err: %v", err)
+	}
+	if len(listing.Articles) != 2 {
+		t.Errorf("should have found both articles by the normalized tag: %v", len(listing.Articles))
+	}
+
+	counts, err := pg.TagsForNewsroom(newsroomAddr)
+	if err != nil {
+		t.Errorf("should not have returned error: err: %v", err)
+	}
+	if len(counts) != 2 {
+		t.Fatalf("should have counted 2 tags: %v", counts)
+	}
+	if counts[0].Slug != "testtag-climate-change" || counts[0].Count != 2 {
+		t.Errorf("should have counted the climate change tag first: %v", counts[0])
+	}
+	if counts[1].Slug != "testtag-politics" || counts[1].Count != 1 {
+		t.Errorf("should have counted the politics tag once: %v", counts[1])
+	}
+
+	first.ArticleMetadata.PrimaryTag = ""
+	first.ArticleMetadata.Tags = []string{"testtag weather"}
+	if err := pg.UpdateArticle(first); err != nil {
+		t.Errorf("should have updated article: err: %v", err)
+	}
+
+	listing, err = pg.ArticlesByTag("testtag-politics", nil, nil)
+	if err != nil {
+		t.Errorf("should not have returned error: err: %v", err)
+	}
+	if len(listing.Articles) != 0 {
+		t.Errorf("should have removed the tag on update")
+	}
+
+	if err := pg.DeleteArticle(second.ID); err != nil {
+		t.Errorf("should have deleted article: err: %v", err)
+	}
+
+	top, err := pg.TopTags(time.Now().Add(-time.Hour), 10)
+	if err != nil {
+		t.Errorf("should not have returned error: err: %v", err)
+	}
+	for _, count := range top {
+		if count.Slug == "testtag-climate-change" {
+			t.Errorf("should not have counted tags of deleted articles")
+		}
+	}
+
+	if err := pg.DB.Where("article_id = ?", first.ID).Delete(&article.ArticleTagGorm{}).Error; err != nil {
+		t.Fatalf("should have removed the tag links: err: %v", err)
+	}
+	if err := pg.BackfillArticleTags(); err != nil {
+		t.Errorf("should have backfilled the tags: err: %v", err)
+	}
+	listing, err = pg.ArticlesByTag("testtag-weather", nil, nil)
+	if err != nil {
+		t.Errorf("should not have returned error: err: %v", err)
+	}
+	if len(listing.Articles) != 1 || listing.Articles[0].ID != first.ID {
+		t.Errorf("should have linked the article to its tags again: %v", len(listing.Articles))
+	}
+}
@@ -0,0 +1,170 @@
+package article
+
+import (
+	"database/sql"
+
+	"github.com/jinzhu/gorm"
+	carticle "github.com/joincivil/go-common/pkg/article"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrNoCanonicalURL indicates that the article has no canonical URL to upsert on
+	ErrNoCanonicalURL = errors.New("article has no canonical url")
+	// ErrDuplicateArticles indicates that a newsroom has more than one article with the same
+	// canonical URL, so the unique index used by UpsertArticle cannot be created
+	ErrDuplicateArticles = errors.New("newsroom has duplicate articles for a canonical url")
+)
+
+// UpsertResult is the outcome of an UpsertArticle call
+type UpsertResult int
+
+const (
+	// UpsertInserted indicates a new article was inserted
+	UpsertInserted UpsertResult = iota + 1
+	// UpsertUpdated indicates an existing article was updated with new content or block data
+	UpsertUpdated
+	// UpsertUnchanged indicates an existing article already had the same content
+	UpsertUnchanged
+)
+
+// String returns the name of the upsert result
+func (u UpsertResult) String() string {
+	switch u {
+	case UpsertInserted:
+		return "inserted"
+	case UpsertUpdated:
+		return "updated"
+	case UpsertUnchanged:
+		return "unchanged"
+	}
+	return "unknown"
+}
+
+// canonicalURLIndexPredicate limits the unique index used by UpsertArticle. It has to
+// match the conflict target predicate in upsertArticleQuery.
+const canonicalURLIndexPredicate = "deleted_at IS NULL AND article_metadata->>'CanonicalURL' <> ''"
+
+// upsertArticleQuery inserts an article or updates the existing one for the newsroom and
+// canonical URL. The update is skipped if the revision content hash has not changed, or
+// if there is no hash and the content is the same, in which case no row is returned.
+// New block data is saved even if the content is the same, so re-anchoring is kept.
+const upsertArticleQuery = `
+INSERT INTO articles
+	(created_at, updated_at, newsroom_address, article_metadata, raw_json, raw_json_text, block_data,
+	indexed_timestamp, tx_hash, block_number, block_hash, tx_status, anchor_state)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (newsroom_address, (article_metadata->>'CanonicalURL'))
+	WHERE ` + canonicalURLIndexPredicate + `
+DO UPDATE SET
+	updated_at = EXCLUDED.updated_at,
+	article_metadata = EXCLUDED.article_metadata,
+	raw_json = EXCLUDED.raw_json,
+	raw_json_text = EXCLUDED.raw_json_text,
+	block_data = COALESCE(EXCLUDED.block_data, articles.block_data),
+	indexed_timestamp = EXCLUDED.indexed_timestamp,
+	tx_hash = CASE WHEN EXCLUDED.block_data IS NULL THEN articles.tx_hash ELSE EXCLUDED.tx_hash END,
+	block_number = CASE WHEN EXCLUDED.block_data IS NULL THEN articles.block_number ELSE EXCLUDED.block_number END,
+	block_hash = CASE WHEN EXCLUDED.block_data IS NULL THEN articles.block_hash ELSE EXCLUDED.block_hash END,
+	tx_status = CASE WHEN EXCLUDED.block_data IS NULL THEN articles.tx_status ELSE EXCLUDED.tx_status END,
+	anchor_state = CASE WHEN EXCLUDED.block_data IS NULL THEN articles.anchor_state ELSE EXCLUDED.anchor_state END
+WHERE articles.article_metadata->>'RevisionContentHash' IS DISTINCT FROM
+		EXCLUDED.article_metadata->>'RevisionContentHash'
+	OR (
+		COALESCE(EXCLUDED.article_metadata->>'RevisionContentHash', '') = ''
+		AND (articles.article_metadata, articles.raw_json) IS DISTINCT FROM
+			(EXCLUDED.article_metadata, EXCLUDED.raw_json)
+	)
+	OR (
+		EXCLUDED.block_data IS NOT NULL
+		AND EXCLUDED.block_data IS DISTINCT FROM articles.block_data
+	)
+RETURNING id, (xmax = 0) AS inserted`
+
+// UpsertArticle inserts the article, or updates the existing article with the same
+// newsroom address and canonical URL. It reports whether the article was inserted,
+// updated or left unchanged because the content hash and block data were equal, and
+// sets the ID of the article. Requires the opt in index from ArticleCanonicalURLUniqueIndex.
+func (p *GormPGPersister) UpsertArticle(article *carticle.Article) (UpsertResult, error) {
+	if article.ArticleMetadata.CanonicalURL == "" {
+		return 0, ErrNoCanonicalURL
+	}
+	if err := p.verifyContentHash(article); err != nil {
+		return 0, err
+	}
+
+	articleGorm := Gorm{}
+	if err := articleGorm.PopulateFromArticle(article); err != nil {
+		return 0, err
+	}
+	// Block data in an upsert is a confirmation of the anchor
+	articleGorm.AnchorState = AnchorStatePending
+	if articleGorm.TxHash != "" {
+		articleGorm.AnchorState = AnchorStateConfirmed
+	}
+
+	var result UpsertResult
+	err := withTransaction(p.DB, func(tx *gorm.DB) error {
+		var err error
+		result, err = upsertArticle(tx, article, &articleGorm)
+		if err != nil || result == UpsertUnchanged {
+			return err
+		}
+		if err := saveArticleAssociations(tx, articleGorm.ID, article); err != nil {
+			return err
+		}
+		return saveRevision(tx, &articleGorm)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	article.ID = articleGorm.ID
+	return result, nil
+}
+
+// upsertArticle runs the upsert query for the article and sets the ID on articleGorm
+func upsertArticle(tx *gorm.DB, article *carticle.Article, articleGorm *Gorm) (UpsertResult, error) {
+	now := gorm.NowFunc()
+	var id uint
+	var inserted bool
+	err := tx.Raw(
+		upsertArticleQuery,
+		now,
+		now,
+		articleGorm.NewsroomAddress,
+		articleGorm.ArticleMetadata,
+		articleGorm.RawJSON,
+		articleGorm.RawJSONText,
+		articleGorm.BlockData,
+		articleGorm.IndexedTimestamp,
+		articleGorm.TxHash,
+		articleGorm.BlockNumber,
+		articleGorm.BlockHash,
+		articleGorm.TxStatus,
+		articleGorm.AnchorState,
+	).Row().Scan(&id, &inserted)
+
+	if err == sql.ErrNoRows {
+		existing := Gorm{}
+		err = tx.Where(
+			"newsroom_address = ? AND article_metadata->>'CanonicalURL' = ?",
+			articleGorm.NewsroomAddress,
+			article.ArticleMetadata.CanonicalURL,
+		).First(&existing).Error
+		if err != nil {
+			return 0, errors.Wrap(err, "error finding unchanged article")
+		}
+		articleGorm.ID = existing.ID
+		return UpsertUnchanged, nil
+
+	} else if err != nil {
+		return 0, err
+	}
+
+	articleGorm.ID = id
+	if inserted {
+		return UpsertInserted, nil
+	}
+	return UpsertUpdated, nil
+}
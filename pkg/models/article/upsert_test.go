@@ -0,0 +1,195 @@
+package article_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/joincivil/go-common-priv/pkg/models/article"
+	"github.com/joincivil/go-common-priv/pkg/models/testutils"
+	carticle "github.com/joincivil/go-common/pkg/article"
+	"github.com/pkg/errors"
+)
+
+func TestUpsertArticle(t *testing.T) {
+	creds := testutils.GetTestDBConnection()
+	pg, err := article.NewGormPGPersister(creds.Host, creds.Port, creds.User, creds.Password, creds.Dbname)
+
+	if err != nil {
+		fmt.Println(err)
+		t.Errorf("threw an error making the persister")
+	}
+
+	testutils.MigrateModels(pg.DB) // nolint: errcheck
+
+	defer pg.DB.Close()
+
+	// The index is opt in, so it is created in a transaction that is rolled back to keep
+	// it from the other tests
+	tx := pg.DB.Begin()
+	defer tx.Rollback()
+	txPersister := uniqueIndexPersister(t, tx)
+
+	upsertURL := "https://newstuff.bz/upsertarticle"
+	narticle := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Title:               "upserted stuff",
+			CanonicalURL:        upsertURL,
+			RevisionContentHash: "0xhash1",
+		},
+		NewsroomAddress: "0x8c722B8AC728aDd7780a66017e8daDBa530EE261",
+	}
+
+	result, err := txPersister.UpsertArticle(narticle)
+	if err != nil {
+		t.Fatalf("should have upserted article: err: %v", err)
+	}
+	if result != article.UpsertInserted {
+		t.Errorf("should have inserted the article: result: %v", result)
+	}
+	if narticle.ID == 0 {
+		t.Errorf("an id should be assigned to the article after upsert")
+	}
+	insertedID := narticle.ID
+
+	narticle.ID = 0
+	result, err = txPersister.UpsertArticle(narticle)
+	if err != nil {
+		t.Errorf("should have upserted article: err: %v", err)
+	}
+	if result != article.UpsertUnchanged {
+		t.Errorf("should have left the article unchanged: result: %v", result)
+	}
+	if narticle.ID != insertedID {
+		t.Errorf("should have set the id of the existing article")
+	}
+
+	narticle.ID = 0
+	narticle.BlockData = testutils.MakeFakeReceipt()
+	result, err = txPersister.UpsertArticle(narticle)
+	if err != nil {
+		t.Errorf("should have upserted article: err: %v", err)
+	}
+	if result != article.UpsertUpdated {
+		t.Errorf("should have saved the block data of the same content: result: %v", result)
+	}
+	anchored, err := txPersister.ArticleByTxHash(testutils.FakeTxHash)
+	if err != nil || anchored.ID != insertedID {
+		t.Errorf("should have anchored the existing article: err: %v", err)
+	}
+
+	narticle.ArticleMetadata.Title = "upserted stuff revised"
+	narticle.ArticleMetadata.RevisionContentHash = "0xhash2"
+	result, err = txPersister.UpsertArticle(narticle)
+	if err != nil {
+		t.Errorf("should have upserted article: err: %v", err)
+	}
+	if result != article.UpsertUpdated {
+		t.Errorf("should have updated the article: result: %v", result)
+	}
+	if narticle.ID != insertedID {
+		t.Errorf("should have updated the existing article")
+	}
+
+	foundarticle, err := txPersister.ArticleByID(insertedID)
+	if err != nil {
+		t.Errorf("threw an error looking up the article: err: %v", err)
+	}
+	if foundarticle.ArticleMetadata.Title != "upserted stuff revised" {
+		t.Errorf("should have saved the updated title")
+	}
+
+	_, err = txPersister.UpsertArticle(&carticle.Article{
+		NewsroomAddress: "0x8c722B8AC728aDd7780a66017e8daDBa530EE261",
+	})
+	if err != article.ErrNoCanonicalURL {
+		t.Errorf("should have returned ErrNoCanonicalURL: err: %v", err)
+	}
+}
+
+func TestArticleCanonicalURLUniqueIndexDuplicates(t *testing.T) {
+	creds := testutils.GetTestDBConnection()
+	pg, err := article.NewGormPGPersister(creds.Host, creds.Port, creds.User, creds.Password, creds.Dbname)
+
+	if err != nil {
+		fmt.Println(err)
+		t.Errorf("threw an error making the persister")
+	}
+
+	testutils.MigrateModels(pg.DB) // nolint: errcheck
+
+	defer pg.DB.Close()
+
+	tx := pg.DB.Begin()
+	defer tx.Rollback()
+
+	txPersister, _ := article.NewGormPGPersisterWithDB(tx)
+	duplicates := []*carticle.Article{}
+	for i := 0; i < 3; i++ {
+		narticle := &carticle.Article{
+			ArticleMetadata: carticle.Metadata{CanonicalURL: "https://newstuff.bz/duplicatearticle"},
+			NewsroomAddress: "0x8c722B8AC728aDd7780a66017e8daDBa530EE261",
+		}
+		if err := txPersister.CreateArticle(narticle); err != nil {
+			t.Fatalf("should have created the duplicate article: err: %v", err)
+		}
+		duplicates = append(duplicates, narticle)
+	}
+
+	err = txPersister.ArticleCanonicalURLUniqueIndex()
+	if errors.Cause(err) != article.ErrDuplicateArticles {
+		t.Errorf("should have returned ErrDuplicateArticles: err: %v", err)
+	}
+
+	deleted, err := txPersister.SoftDeleteDuplicateArticles()
+	if err != nil {
+		t.Fatalf("should have soft deleted the duplicates: err: %v", err)
+	}
+	for _, duplicate := range duplicates[:2] {
+		if !containsID(deleted, duplicate.ID) {
+			t.Errorf("should have returned the id of the older duplicate %v: %v", duplicate.ID, deleted)
+		}
+	}
+	if containsID(deleted, duplicates[2].ID) {
+		t.Errorf("should not have returned the id of the most recent duplicate")
+	}
+
+	if err := txPersister.ArticleCanonicalURLUniqueIndex(); err != nil {
+		t.Fatalf("should have created the index without the duplicates: err: %v", err)
+	}
+
+	found, err := txPersister.ArticleByCanonicalURL("https://newstuff.bz/duplicatearticle")
+	if err != nil || found.ID != duplicates[2].ID {
+		t.Errorf("should have kept the most recent duplicate: err: %v", err)
+	}
+	for _, duplicate := range duplicates[:2] {
+		if _, err := txPersister.ArticleByID(duplicate.ID); err == nil {
+			t.Errorf("should have soft deleted the older duplicate %v", duplicate.ID)
+		}
+		if _, err := txPersister.ArticleByID(duplicate.ID, article.IncludeDeleted()); err != nil {
+			t.Errorf("should have kept the older duplicate: err: %v", err)
+		}
+	}
+}
+
+// uniqueIndexPersister returns a persister for tx with the opt in canonical URL index.
+// Duplicates left in the test db by other tests are soft deleted in tx first.
+func uniqueIndexPersister(t *testing.T, tx *gorm.DB) *article.GormPGPersister {
+	txPersister, _ := article.NewGormPGPersisterWithDB(tx)
+	if _, err := txPersister.SoftDeleteDuplicateArticles(); err != nil {
+		t.Fatalf("should have soft deleted the duplicates: err: %v", err)
+	}
+	if err := txPersister.ArticleCanonicalURLUniqueIndex(); err != nil {
+		t.Fatalf("should have created the unique index: err: %v", err)
+	}
+	return txPersister
+}
+
+func containsID(ids []uint, id uint) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+// Package listcursor contains the keyset page cursors and page limits shared by the
+// article persisters, so they page in the same way
+package listcursor
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultLimit is the number of items on a page if no limit is requested
+	DefaultLimit = 20
+	// MaxLimit is the maximum number of items on a page
+	MaxLimit = 500
+)
+
+var (
+	// ErrInvalid indicates that the page cursor could not be decoded
+	ErrInvalid = errors.New("invalid page cursor")
+)
+
+// Cursor is the keyset position of the last article on a page
+type Cursor struct {
+	IndexedTimestamp time.Time
+	ID               uint
+}
+
+// Encode returns the cursor as an opaque string
+func (c Cursor) Encode() string {
+	s := fmt.Sprintf("%d:%d", c.IndexedTimestamp.UnixNano(), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+// Decode returns the cursor encoded in the string. Returns ErrInvalid if it could not
+// be decoded.
+func Decode(cursor string) (*Cursor, error) {
+	bys, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+
+	parts := strings.Split(string(bys), ":")
+	if len(parts) != 2 {
+		return nil, ErrInvalid
+	}
+
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	id, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+
+	return &Cursor{IndexedTimestamp: time.Unix(0, nanos).UTC(), ID: uint(id)}, nil
+}
+
+// Limit returns the number of items on a page for the requested limit
+func Limit(limit int) int {
+	if limit <= 0 {
+		return DefaultLimit
+	}
+	if limit > MaxLimit {
+		return MaxLimit
+	}
+	return limit
+}
@@ -0,0 +1,230 @@
+// Package migrations contains the versioned schema migrations of the models and the
+// runner that applies them. Applied migrations are recorded in the schema_migrations
+// table, and runs hold a postgres advisory lock so concurrent deploys apply each
+// migration once.
+package migrations
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/jinzhu/gorm"
+
+	gormutils "github.com/joincivil/go-common-priv/pkg/utils/gorm"
+)
+
+const (
+	// DefaultTableName is the table the applied migrations are recorded in
+	DefaultTableName = "schema_migrations"
+
+	// advisoryLockKey is the key of the transaction level advisory lock held while
+	// migrations run, "civilmig" in ascii. It only has to be distinct from other advisory
+	// locks in the db.
+	advisoryLockKey int64 = 0x636976696c6d6967
+)
+
+var (
+	// ErrInvalidMigrations indicates a migration list that is not ordered by version or
+	// has duplicate or non positive versions
+	ErrInvalidMigrations = errors.New("invalid migrations")
+	// ErrUnknownVersion indicates a version that is not in the migration list
+	ErrUnknownVersion = errors.New("unknown migration version")
+	// ErrIrreversible indicates a migration without a down migration
+	ErrIrreversible = errors.New("migration is irreversible")
+)
+
+// Migration is a versioned schema change. Up applies the change and Down reverts it,
+// both in the transaction the runner holds the lock in.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// Exec returns a migration func that executes the queries in order
+func Exec(queries ...string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, query := range queries {
+			if err := tx.Exec(query).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// AppliedMigration is a migration recorded as applied
+type AppliedMigration struct {
+	Version   int64
+	Name      string
+	AppliedAt time.Time
+}
+
+// Runner applies and reverts a list of migrations on a db
+type Runner struct {
+	DB         *gorm.DB
+	Migrations []Migration
+	// TableName is the table the applied migrations are recorded in
+	TableName string
+}
+
+// NewRunner returns a runner for the migrations, which have to be ordered by version
+func NewRunner(db *gorm.DB, migrations []Migration) (*Runner, error) {
+	for i, migration := range migrations {
+		if migration.Version <= 0 || (i > 0 && migration.Version <= migrations[i-1].Version) {
+			return nil, errors.Wrapf(ErrInvalidMigrations, "version %v of %q", migration.Version, migration.Name)
+		}
+		if migration.Up == nil {
+			return nil, errors.Wrapf(ErrInvalidMigrations, "no up migration for version %v", migration.Version)
+		}
+	}
+	return &Runner{DB: db, Migrations: migrations, TableName: DefaultTableName}, nil
+}
+
+// Up applies the models migrations to the db
+func Up(db *gorm.DB) error {
+	runner, err := NewRunner(db, All())
+	if err != nil {
+		return err
+	}
+	return runner.Up()
+}
+
+// Up applies all pending migrations
+func (r *Runner) Up() error {
+	if len(r.Migrations) == 0 {
+		return nil
+	}
+	return r.UpTo(r.Migrations[len(r.Migrations)-1].Version)
+}
+
+// UpTo applies the pending migrations up to and including the given version. Pending
+// migrations older than the latest applied one, such as ones merged from another branch,
+// are applied as well.
+func (r *Runner) UpTo(version int64) error {
+	if r.migration(version) == nil {
+		return errors.Wrapf(ErrUnknownVersion, "version %v", version)
+	}
+
+	return r.run(func(tx *gorm.DB, applied map[int64]bool) error {
+		for _, migration := range r.Migrations {
+			if migration.Version > version || applied[migration.Version] {
+				continue
+			}
+			if err := migration.Up(tx); err != nil {
+				return errors.Wrapf(err, "error applying migration %v %q", migration.Version, migration.Name)
+			}
+			err := tx.Exec(
+				"INSERT INTO "+r.TableName+" (version, name, applied_at) VALUES (?, ?, now())",
+				migration.Version,
+				migration.Name,
+			).Error
+			if err != nil {
+				return errors.Wrapf(err, "error recording migration %v", migration.Version)
+			}
+		}
+		return nil
+	})
+}
+
+// DownTo reverts the applied migrations newer than the given version, newest first. A
+// version of 0 reverts all of them.
+func (r *Runner) DownTo(version int64) error {
+	if version != 0 && r.migration(version) == nil {
+		return errors.Wrapf(ErrUnknownVersion, "version %v", version)
+	}
+
+	return r.run(func(tx *gorm.DB, applied map[int64]bool) error {
+		versions := make([]int64, 0, len(applied))
+		for v := range applied {
+			if v > version {
+				versions = append(versions, v)
+			}
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		for _, v := range versions {
+			migration := r.migration(v)
+			if migration == nil {
+				return errors.Wrapf(ErrUnknownVersion, "applied version %v", v)
+			}
+			if migration.Down == nil {
+				return errors.Wrapf(ErrIrreversible, "version %v %q", v, migration.Name)
+			}
+			if err := migration.Down(tx); err != nil {
+				return errors.Wrapf(err, "error reverting migration %v %q", v, migration.Name)
+			}
+			if err := tx.Exec("DELETE FROM "+r.TableName+" WHERE version = ?", v).Error; err != nil {
+				return errors.Wrapf(err, "error removing migration %v", v)
+			}
+		}
+		return nil
+	})
+}
+
+// Applied returns the applied migrations ordered by version
+func (r *Runner) Applied() ([]AppliedMigration, error) {
+	applied := []AppliedMigration{}
+	if !r.DB.HasTable(r.TableName) {
+		return applied, nil
+	}
+	if err := r.DB.Table(r.TableName).Order("version ASC").Find(&applied).Error; err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// Version returns the latest applied version, 0 if none are applied
+func (r *Runner) Version() (int64, error) {
+	applied, err := r.Applied()
+	if err != nil || len(applied) == 0 {
+		return 0, err
+	}
+	return applied[len(applied)-1].Version, nil
+}
+
+// run calls fn with the applied versions in a transaction holding the advisory lock,
+// so runs in concurrent deploys wait for each other and see each others migrations
+func (r *Runner) run(fn func(tx *gorm.DB, applied map[int64]bool) error) error {
+	// Migrations are not safe to retry once they ran
+	opts := &gormutils.TxOptions{MaxAttempts: 1}
+	return gormutils.WithTxOptions(r.DB, opts, func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", advisoryLockKey).Error; err != nil {
+			return errors.Wrap(err, "error acquiring migration lock")
+		}
+
+		err := tx.Exec(
+			"CREATE TABLE IF NOT EXISTS " + r.TableName + " (" +
+				"version bigint PRIMARY KEY, " +
+				"name text NOT NULL, " +
+				"applied_at timestamp with time zone NOT NULL)",
+		).Error
+		if err != nil {
+			return errors.Wrap(err, "error creating migrations table")
+		}
+
+		versions := []int64{}
+		if err := tx.Table(r.TableName).Pluck("version", &versions).Error; err != nil {
+			return errors.Wrap(err, "error reading applied migrations")
+		}
+		applied := make(map[int64]bool, len(versions))
+		for _, v := range versions {
+			applied[v] = true
+		}
+
+		return fn(tx, applied)
+	})
+}
+
+// migration returns the migration with the given version, nil if there is none
+func (r *Runner) migration(version int64) *Migration {
+	for i := range r.Migrations {
+		if r.Migrations[i].Version == version {
+			return &r.Migrations[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,372 @@
+package migrations_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+
+	"github.com/joincivil/go-common-priv/pkg/models/migrations"
+	"github.com/joincivil/go-common-priv/pkg/models/testutils"
+	gormutils "github.com/joincivil/go-common-priv/pkg/utils/gorm"
+)
+
+const testTableName = "schema_migrations_test"
+
+func testConnection(t *testing.T) *gorm.DB {
+	creds := testutils.GetTestDBConnection()
+	db, err := gormutils.NewGormPGConnectionWithConfig(
+		gormutils.NewConfig(creds.Host, creds.Port, creds.User, creds.Password, creds.Dbname),
+	)
+	if err != nil {
+		t.Fatalf("threw an error making the connection: err: %v", err)
+	}
+	return db
+}
+
+// testMigrations create and drop tables without IF NOT EXISTS, so they fail if they
+// are applied or reverted twice
+func testMigrations() []migrations.Migration {
+	return []migrations.Migration{
+		{
+			Version: 1,
+			Name:    "create_migrations_test_a",
+			Up:      migrations.Exec("CREATE TABLE migrations_test_a (id serial PRIMARY KEY)"),
+			Down:    migrations.Exec("DROP TABLE migrations_test_a"),
+		},
+		{
+			Version: 2,
+			Name:    "create_migrations_test_b",
+			Up:      migrations.Exec("CREATE TABLE migrations_test_b (id serial PRIMARY KEY)"),
+			Down:    migrations.Exec("DROP TABLE migrations_test_b"),
+		},
+	}
+}
+
+func testRunner(t *testing.T, db *gorm.DB) *migrations.Runner {
+	runner, err := migrations.NewRunner(db, testMigrations())
+	if err != nil {
+		t.Fatalf("should have created the runner: err: %v", err)
+	}
+	runner.TableName = testTableName
+	return runner
+}
+
+func cleanupTestMigrations(db *gorm.DB) {
+	db.Exec("DROP TABLE IF EXISTS migrations_test_a, migrations_test_b, " + testTableName)
+}
+
+func TestNewRunner(t *testing.T) {
+	up := migrations.Exec()
+	invalid := [][]migrations.Migration{
+		{{Version: 0, Up: up}},
+		{{Version: 2, Up: up}, {Version: 1, Up: up}},
+		{{Version: 1, Up: up}, {Version: 1, Up: up}},
+		{{Version: 1}},
+	}
+	for _, list := range invalid {
+		if _, err := migrations.NewRunner(nil, list); errors.Cause(err) != migrations.ErrInvalidMigrations {
+			t.Errorf("should have returned ErrInvalidMigrations for %v: err: %v", list, err)
+		}
+	}
+
+	runner, err := migrations.NewRunner(nil, migrations.All())
+	if err != nil {
+		t.Fatalf("should have accepted the models migrations: err: %v", err)
+	}
+	if runner.TableName != migrations.DefaultTableName {
+		t.Errorf("should have defaulted the table name: %v", runner.TableName)
+	}
+	if err := runner.UpTo(1000); errors.Cause(err) != migrations.ErrUnknownVersion {
+		t.Errorf("should have returned ErrUnknownVersion: err: %v", err)
+	}
+}
+
+func TestRunnerUpDown(t *testing.T) {
+	db := testConnection(t)
+	defer db.Close()
+	cleanupTestMigrations(db)
+	defer cleanupTestMigrations(db)
+
+	runner := testRunner(t, db)
+	if version, err := runner.Version(); err != nil || version != 0 {
+		t.Errorf("should not have applied any migrations: %v: err: %v", version, err)
+	}
+
+	if err := runner.UpTo(1); err != nil {
+		t.Fatalf("should have applied the first migration: err: %v", err)
+	}
+	if !db.HasTable("migrations_test_a") || db.HasTable("migrations_test_b") {
+		t.Errorf("should have only applied the first migration")
+	}
+
+	if err := runner.Up(); err != nil {
+		t.Fatalf("should have applied the pending migrations: err: %v", err)
+	}
+	if err := runner.Up(); err != nil {
+		t.Fatalf("should not have applied the migrations again: err: %v", err)
+	}
+	applied, err := runner.Applied()
+	if err != nil {
+		t.Fatalf("should have returned the applied migrations: err: %v", err)
+	}
+	if len(applied) != 2 || applied[1].Name != "create_migrations_test_b" || applied[1].AppliedAt.IsZero() {
+		t.Errorf("should have recorded the applied migrations: %v", applied)
+	}
+
+	if err := runner.DownTo(1); err != nil {
+		t.Fatalf("should have reverted the second migration: err: %v", err)
+	}
+	if !db.HasTable("migrations_test_a") || db.HasTable("migrations_test_b") {
+		t.Errorf("should have only reverted the second migration")
+	}
+	if version, err := runner.Version(); err != nil || version != 1 {
+		t.Errorf("should have removed the reverted migration: %v: err: %v", version, err)
+	}
+
+	if err := runner.DownTo(0); err != nil {
+		t.Fatalf("should have reverted all migrations: err: %v", err)
+	}
+	if db.HasTable("migrations_test_a") {
+		t.Errorf("should have reverted the first migration")
+	}
+}
+
+func TestRunnerFailedMigration(t *testing.T) {
+	db := testConnection(t)
+	defer db.Close()
+	cleanupTestMigrations(db)
+	defer cleanupTestMigrations(db)
+
+	list := append(testMigrations(), migrations.Migration{
+		Version: 3,
+		Name:    "fails",
+		Up:      migrations.Exec("SELECT * FROM migrations_test_none"),
+	})
+	runner, err := migrations.NewRunner(db, list)
+	if err != nil {
+		t.Fatalf("should have created the runner: err: %v", err)
+	}
+	runner.TableName = testTableName
+
+	if err := runner.Up(); err == nil {
+		t.Fatalf("should have returned the error of the failed migration")
+	}
+	if db.HasTable("migrations_test_a") {
+		t.Errorf("should have rolled back the migrations of the run")
+	}
+
+	if err := runner.UpTo(2); err != nil {
+		t.Fatalf("should have applied the migrations before the failed one: err: %v", err)
+	}
+	if err := runner.DownTo(0); err != nil {
+		t.Fatalf("should have reverted the migrations: err: %v", err)
+	}
+}
+
+func TestRunnerConcurrentUp(t *testing.T) {
+	db := testConnection(t)
+	defer db.Close()
+	cleanupTestMigrations(db)
+	defer cleanupTestMigrations(db)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		runner := testRunner(t, db)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- runner.Up()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("should have applied each migration once: err: %v", err)
+		}
+	}
+	applied, err := testRunner(t, db).Applied()
+	if err != nil || len(applied) != 2 {
+		t.Errorf("should have recorded each migration once: %v: err: %v", applied, err)
+	}
+}
+
+func TestUp(t *testing.T) {
+	db := testConnection(t)
+	defer db.Close()
+
+	for i := 0; i < 2; i++ {
+		if err := migrations.Up(db); err != nil {
+			t.Fatalf("should have migrated the models: err: %v", err)
+		}
+	}
+
+	all := migrations.All()
+	runner, _ := migrations.NewRunner(db, all)
+	version, err := runner.Version()
+	if err != nil || version != all[len(all)-1].Version {
+		t.Errorf("should have applied all migrations: %v: err: %v", version, err)
+	}
+
+	for _, table := range []string{"newsrooms", "articles", "article_revisions", "tags", "article_images"} {
+		if !db.HasTable(table) {
+			t.Errorf("should have created the %v table", table)
+		}
+	}
+	var count int
+	err = db.Raw("SELECT count(*) FROM pg_indexes WHERE indexname = ?", "idx_articles_raw_json").Row().Scan(&count)
+	if err != nil || count != 1 {
+		t.Errorf("should have created the raw_json index: %v: err: %v", count, err)
+	}
+}
+
+func TestDataMigrations(t *testing.T) {
+	db := testConnection(t)
+	defer db.Close()
+	if err := migrations.Up(db); err != nil {
+		t.Fatalf("should have migrated the models: err: %v", err)
+	}
+
+	tx := db.Begin()
+	defer tx.Rollback()
+	all := migrations.All()
+	migration := func(version int64) migrations.Migration {
+		for _, m := range all {
+			if m.Version == version {
+				return m
+			}
+		}
+		t.Fatalf("should have found migration %v", version)
+		return migrations.Migration{}
+	}
+
+	var id int64
+	err := tx.Raw(
+		`INSERT INTO articles (newsroom_address, article_metadata, block_data, anchor_state)
+		VALUES ('0xmigrations', '{"CanonicalURL": "https://newstuff.bz/migrations"}',
+			'{"transactionHash": "0xabc", "blockNumber": "0x10", "status": "0x1",
+			"blockHash": "0x0000000000000000000000000000000000000000000000000000000000000000"}', 'pending')
+		RETURNING id`,
+	).Row().Scan(&id)
+	if err != nil {
+		t.Fatalf("should have inserted the article: err: %v", err)
+	}
+
+	var linkedID int64
+	err = tx.Raw(
+		`INSERT INTO articles (newsroom_address, article_metadata, raw_json)
+		VALUES ('0xmigrations', '{
+			"CanonicalURL": "https://newstuff.bz/migrations-links",
+			"PrimaryTag": "Migration Science",
+			"Tags": ["  Migration Backfills ", "migration-backfills", "migration science", "!!"],
+			"Contributors": [
+				{"Name": "  Migration   WRITER ", "Role": "author"},
+				{"Name": "migration writer", "Role": "author"},
+				{"Name": "Migration Writer", "Role": "editor"},
+				{"Name": "   ", "Role": "author"}
+			],
+			"Images": [
+				{"URL": "https://newstuff.bz/migrations.png", "Hash": "", "H": 10, "W": 20},
+				{"URL": "https://newstuff.bz/migrations-hashed.png", "Hash": "0xmigrationshash", "H": 0, "W": 0},
+				{"URL": "", "Hash": "", "H": 0, "W": 0}
+			]
+		}', '{"title": "migrations"}')
+		RETURNING id`,
+	).Row().Scan(&linkedID)
+	if err != nil {
+		t.Fatalf("should have inserted the article: err: %v", err)
+	}
+
+	lowerAddress := "0x7c722b8ac728add7780a66017e8dadba530ee261"
+	err = tx.Exec("INSERT INTO articles (newsroom_address) VALUES (?)", lowerAddress).Error
+	if err != nil {
+		t.Fatalf("should have inserted the article: err: %v", err)
+	}
+
+	for _, version := range []int64{6, 7, 13, 16, 17, 18, 19} {
+		if err := migration(version).Up(tx); err != nil {
+			t.Fatalf("should have applied migration %v again: err: %v", version, err)
+		}
+	}
+
+	var normalized int
+	err = tx.Raw(
+		"SELECT count(*) FROM articles WHERE newsroom_address = ?",
+		"0x7c722B8AC728aDd7780a66017e8daDBa530EE261",
+	).Row().Scan(&normalized)
+	if err != nil || normalized == 0 {
+		t.Errorf("should have normalized the newsroom address: %v: err: %v", normalized, err)
+	}
+
+	var txHash, blockHash, anchorState string
+	var blockNumber, txStatus int64
+	err = tx.Raw(
+		"SELECT tx_hash, block_number, block_hash, tx_status, anchor_state FROM articles WHERE id = ?",
+		id,
+	).Row().Scan(&txHash, &blockNumber, &blockHash, &txStatus, &anchorState)
+	if err != nil {
+		t.Fatalf("should have found the article: err: %v", err)
+	}
+	if txHash != "0xabc" || blockNumber != 16 || blockHash != "" || txStatus != 1 {
+		t.Errorf("should have backfilled the block data columns: %v %v %v %v", txHash, blockNumber, blockHash, txStatus)
+	}
+	if anchorState != "confirmed" {
+		t.Errorf("should have confirmed the article with block data: %v", anchorState)
+	}
+
+	var rawJSONText string
+	err = tx.Raw("SELECT raw_json_text FROM articles WHERE id = ?", linkedID).Row().Scan(&rawJSONText)
+	if err != nil || rawJSONText != `{"title": "migrations"}` {
+		t.Errorf("should have backfilled the raw json text: %v: err: %v", rawJSONText, err)
+	}
+
+	tags := map[string]bool{}
+	rows, err := tx.Raw(
+		`SELECT tags.slug, article_tags."primary" FROM article_tags
+		JOIN tags ON tags.id = article_tags.tag_id WHERE article_tags.article_id = ?`,
+		linkedID,
+	).Rows()
+	if err != nil {
+		t.Fatalf("should have found the article tags: err: %v", err)
+	}
+	for rows.Next() {
+		var slug string
+		var primary bool
+		if err := rows.Scan(&slug, &primary); err != nil {
+			t.Fatalf("should have scanned the article tag: err: %v", err)
+		}
+		tags[slug] = primary
+	}
+	rows.Close() // nolint: errcheck
+	if len(tags) != 2 || !tags["migration-science"] || tags["migration-backfills"] {
+		t.Errorf("should have linked the normalized tags: %v", tags)
+	}
+
+	var contributorLinks int
+	err = tx.Raw(
+		`SELECT count(*) FROM article_contributors
+		JOIN contributors ON contributors.id = article_contributors.contributor_id
+		WHERE article_contributors.article_id = ? AND contributors.normalized_name = 'migration writer'
+			AND contributors.address = ''`,
+		linkedID,
+	).Row().Scan(&contributorLinks)
+	if err != nil || contributorLinks != 2 {
+		t.Errorf("should have linked the contributor once per role: %v: err: %v", contributorLinks, err)
+	}
+
+	var imageLinks int
+	err = tx.Raw(
+		`SELECT count(*) FROM article_image_links
+		JOIN article_images ON article_images.id = article_image_links.image_id
+		WHERE article_image_links.article_id = ?
+			AND article_images.image_key IN ('url:https://newstuff.bz/migrations.png', '0xmigrationshash')`,
+		linkedID,
+	).Row().Scan(&imageLinks)
+	if err != nil || imageLinks != 2 {
+		t.Errorf("should have linked the images: %v: err: %v", imageLinks, err)
+	}
+}
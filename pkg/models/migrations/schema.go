@@ -0,0 +1,553 @@
+package migrations
+
+import (
+	log "github.com/golang/glog"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+
+	ceth "github.com/joincivil/go-common/pkg/eth"
+)
+
+// The first migrations create the schema AutoMigrate and the index helpers of the
+// article persister used to set up, the helpers are deprecated and run the migrations
+// now. They only create what does not exist yet, so dbs set up that way adopt the
+// migrations without changes. The schema and the data changes are written out rather
+// than derived from the models or run with the persisters, so later changes to them need
+// a new migration instead of changing these.
+
+// All returns the migrations of the models, ordered by version
+func All() []Migration {
+	return []Migration{
+		{
+			Version: 1,
+			Name:    "create_newsrooms",
+			Up: Exec(
+				`CREATE TABLE IF NOT EXISTS newsrooms (
+					id serial,
+					created_at timestamp with time zone,
+					updated_at timestamp with time zone,
+					deleted_at timestamp with time zone,
+					name text,
+					address text NOT NULL UNIQUE,
+					meta jsonb,
+					PRIMARY KEY (id)
+				)`,
+				"CREATE INDEX IF NOT EXISTS idx_newsrooms_deleted_at ON newsrooms (deleted_at)",
+			),
+			Down: Exec("DROP TABLE IF EXISTS newsrooms"),
+		},
+		{
+			Version: 2,
+			Name:    "create_articles",
+			Up: Exec(
+				`CREATE TABLE IF NOT EXISTS articles (
+					id serial,
+					created_at timestamp with time zone,
+					updated_at timestamp with time zone,
+					deleted_at timestamp with time zone,
+					block_data jsonb,
+					article_metadata jsonb,
+					newsroom_address text,
+					indexed_timestamp timestamp with time zone,
+					raw_json jsonb,
+					PRIMARY KEY (id)
+				)`,
+				"CREATE INDEX IF NOT EXISTS idx_articles_deleted_at ON articles (deleted_at)",
+			),
+			Down: Exec("DROP TABLE IF EXISTS articles"),
+		},
+		{
+			Version: 3,
+			Name:    "create_articles_raw_json_index",
+			Up:      Exec("CREATE INDEX IF NOT EXISTS idx_articles_raw_json ON articles USING gin (raw_json)"),
+			Down:    Exec("DROP INDEX IF EXISTS idx_articles_raw_json"),
+		},
+		{
+			Version: 4,
+			Name:    "create_articles_lookup_indexes",
+			Up: Exec(
+				"CREATE INDEX IF NOT EXISTS idx_articles_metadata_canonicalurl "+
+					"ON articles ((article_metadata->>'CanonicalURL'))",
+				"CREATE INDEX IF NOT EXISTS idx_articles_metadata_revisioncontenthash "+
+					"ON articles ((article_metadata->>'RevisionContentHash'))",
+				"CREATE INDEX IF NOT EXISTS idx_articles_indexed_timestamp_id ON articles (indexed_timestamp, id)",
+			),
+			Down: Exec(
+				"DROP INDEX IF EXISTS idx_articles_indexed_timestamp_id",
+				"DROP INDEX IF EXISTS idx_articles_metadata_revisioncontenthash",
+				"DROP INDEX IF EXISTS idx_articles_metadata_canonicalurl",
+			),
+		},
+		{
+			Version: 5,
+			Name:    "create_article_revisions",
+			Up: Exec(
+				`CREATE TABLE IF NOT EXISTS article_revisions (
+					id serial,
+					created_at timestamp with time zone,
+					updated_at timestamp with time zone,
+					deleted_at timestamp with time zone,
+					article_id integer NOT NULL,
+					revision_content_hash text NOT NULL,
+					revision_content_url text,
+					revision_date timestamp with time zone,
+					newsroom_address text,
+					indexed_timestamp timestamp with time zone,
+					article_metadata jsonb,
+					block_data jsonb,
+					raw_json jsonb,
+					PRIMARY KEY (id)
+				)`,
+				"CREATE INDEX IF NOT EXISTS idx_article_revisions_deleted_at ON article_revisions (deleted_at)",
+				"CREATE UNIQUE INDEX IF NOT EXISTS idx_article_revisions_article_id_hash "+
+					"ON article_revisions (article_id, revision_content_hash)",
+			),
+			Down: Exec("DROP TABLE IF EXISTS article_revisions"),
+		},
+		{
+			Version: 6,
+			Name:    "add_articles_block_data_columns",
+			Up: Exec(
+				"ALTER TABLE articles ADD COLUMN IF NOT EXISTS tx_hash text",
+				"ALTER TABLE articles ADD COLUMN IF NOT EXISTS block_number bigint",
+				"ALTER TABLE articles ADD COLUMN IF NOT EXISTS block_hash text",
+				"ALTER TABLE articles ADD COLUMN IF NOT EXISTS tx_status bigint",
+				"CREATE INDEX IF NOT EXISTS idx_articles_tx_hash ON articles (tx_hash)",
+				"CREATE INDEX IF NOT EXISTS idx_articles_block_number ON articles (block_number)",
+				`CREATE TABLE IF NOT EXISTS article_block_data_history (
+					id serial,
+					created_at timestamp with time zone,
+					updated_at timestamp with time zone,
+					deleted_at timestamp with time zone,
+					article_id integer NOT NULL,
+					block_data jsonb,
+					tx_hash text,
+					block_number bigint,
+					block_hash text,
+					reason text,
+					PRIMARY KEY (id)
+				)`,
+				"CREATE INDEX IF NOT EXISTS idx_article_block_data_history_deleted_at "+
+					"ON article_block_data_history (deleted_at)",
+				"CREATE INDEX IF NOT EXISTS idx_article_block_data_history_article_id "+
+					"ON article_block_data_history (article_id)",
+				// Copies the block data of existing articles to the columns, the hex
+				// numbers are converted to bigints
+				`UPDATE articles SET
+					tx_hash = block_data->>'transactionHash',
+					block_number = COALESCE(('x' || lpad(substr(block_data->>'blockNumber', 3), 16, '0'))::bit(64)::bigint, 0),
+					block_hash = CASE
+						WHEN block_data->>'blockHash' = '0x0000000000000000000000000000000000000000000000000000000000000000' THEN ''
+						ELSE COALESCE(block_data->>'blockHash', '')
+					END,
+					tx_status = COALESCE(('x' || lpad(substr(block_data->>'status', 3), 16, '0'))::bit(64)::bigint, 0)
+				WHERE block_data IS NOT NULL AND COALESCE(tx_hash, '') = ''`,
+			),
+			Down: Exec(
+				"DROP TABLE IF EXISTS article_block_data_history",
+				"ALTER TABLE articles DROP COLUMN IF EXISTS tx_status",
+				"ALTER TABLE articles DROP COLUMN IF EXISTS block_hash",
+				"ALTER TABLE articles DROP COLUMN IF EXISTS block_number",
+				"ALTER TABLE articles DROP COLUMN IF EXISTS tx_hash",
+			),
+		},
+		{
+			Version: 7,
+			Name:    "add_articles_anchor_state",
+			Up: Exec(
+				"ALTER TABLE articles ADD COLUMN IF NOT EXISTS anchor_state text DEFAULT 'pending'",
+				"CREATE INDEX IF NOT EXISTS idx_articles_anchor_state ON articles (anchor_state)",
+				"UPDATE articles SET anchor_state = 'confirmed' WHERE block_data IS NOT NULL "+
+					"AND (anchor_state IS NULL OR anchor_state = 'pending')",
+			),
+			Down: Exec("ALTER TABLE articles DROP COLUMN IF EXISTS anchor_state"),
+		},
+		{
+			Version: 8,
+			Name:    "add_articles_search_vector",
+			Up: Exec(
+				"ALTER TABLE articles ADD COLUMN IF NOT EXISTS search_vector tsvector",
+				`CREATE OR REPLACE FUNCTION articles_search_vector_update() RETURNS trigger AS $$
+				BEGIN
+					NEW.search_vector :=
+						setweight(to_tsvector('english', coalesce(NEW.article_metadata->>'Title', '')), 'A') ||
+						setweight(to_tsvector('english', coalesce(NEW.article_metadata->>'Description', '')), 'B') ||
+						setweight(to_tsvector('english',
+							coalesce(NEW.raw_json->>'content', NEW.raw_json->>'body', '')), 'C');
+					RETURN NEW;
+				END
+				$$ LANGUAGE plpgsql`,
+				"DROP TRIGGER IF EXISTS articles_search_vector_trigger ON articles",
+				"CREATE TRIGGER articles_search_vector_trigger BEFORE INSERT OR UPDATE ON articles "+
+					"FOR EACH ROW EXECUTE PROCEDURE articles_search_vector_update()",
+				"CREATE INDEX IF NOT EXISTS idx_articles_search_vector ON articles USING gin (search_vector)",
+				// Setting the rows to themselves runs the trigger
+				"UPDATE articles SET id = id WHERE search_vector IS NULL",
+			),
+			Down: Exec(
+				"DROP TRIGGER IF EXISTS articles_search_vector_trigger ON articles",
+				"DROP FUNCTION IF EXISTS articles_search_vector_update()",
+				"ALTER TABLE articles DROP COLUMN IF EXISTS search_vector",
+			),
+		},
+		{
+			Version: 9,
+			Name:    "create_tags",
+			Up: Exec(
+				`CREATE TABLE IF NOT EXISTS tags (
+					id serial,
+					created_at timestamp with time zone,
+					updated_at timestamp with time zone,
+					slug text NOT NULL,
+					name text,
+					PRIMARY KEY (id)
+				)`,
+				"CREATE UNIQUE INDEX IF NOT EXISTS uix_tags_slug ON tags (slug)",
+				`CREATE TABLE IF NOT EXISTS article_tags (
+					id serial,
+					article_id integer NOT NULL,
+					tag_id integer NOT NULL,
+					"primary" boolean,
+					PRIMARY KEY (id)
+				)`,
+				"CREATE UNIQUE INDEX IF NOT EXISTS idx_article_tags_article_id_tag_id "+
+					"ON article_tags (article_id, tag_id)",
+				"CREATE INDEX IF NOT EXISTS idx_article_tags_tag_id ON article_tags (tag_id)",
+			),
+			Down: Exec(
+				"DROP TABLE IF EXISTS article_tags",
+				"DROP TABLE IF EXISTS tags",
+			),
+		},
+		{
+			Version: 10,
+			Name:    "create_contributors",
+			Up: Exec(
+				`CREATE TABLE IF NOT EXISTS contributors (
+					id serial,
+					created_at timestamp with time zone,
+					updated_at timestamp with time zone,
+					normalized_name text NOT NULL,
+					address text NOT NULL,
+					name text,
+					PRIMARY KEY (id)
+				)`,
+				"CREATE UNIQUE INDEX IF NOT EXISTS idx_contributors_normalized_name_address "+
+					"ON contributors (normalized_name, address)",
+				`CREATE TABLE IF NOT EXISTS article_contributors (
+					id serial,
+					article_id integer NOT NULL,
+					contributor_id integer NOT NULL,
+					role text,
+					PRIMARY KEY (id)
+				)`,
+				"CREATE UNIQUE INDEX IF NOT EXISTS idx_article_contributors_article_id_contributor_id_role "+
+					"ON article_contributors (article_id, contributor_id, role)",
+				"CREATE INDEX IF NOT EXISTS idx_article_contributors_contributor_id "+
+					"ON article_contributors (contributor_id)",
+			),
+			Down: Exec(
+				"DROP TABLE IF EXISTS article_contributors",
+				"DROP TABLE IF EXISTS contributors",
+			),
+		},
+		{
+			Version: 11,
+			Name:    "create_article_images",
+			Up: Exec(
+				`CREATE TABLE IF NOT EXISTS article_images (
+					id serial,
+					created_at timestamp with time zone,
+					updated_at timestamp with time zone,
+					image_key text NOT NULL,
+					hash text,
+					url text,
+					h integer,
+					w integer,
+					PRIMARY KEY (id)
+				)`,
+				"CREATE UNIQUE INDEX IF NOT EXISTS uix_article_images_image_key ON article_images (image_key)",
+				"CREATE INDEX IF NOT EXISTS idx_article_images_url ON article_images (url)",
+				`CREATE TABLE IF NOT EXISTS article_image_links (
+					id serial,
+					article_id integer NOT NULL,
+					image_id integer NOT NULL,
+					position integer,
+					PRIMARY KEY (id)
+				)`,
+				"CREATE UNIQUE INDEX IF NOT EXISTS idx_article_image_links_article_id_image_id "+
+					"ON article_image_links (article_id, image_id)",
+				"CREATE INDEX IF NOT EXISTS idx_article_image_links_image_id ON article_image_links (image_id)",
+			),
+			Down: Exec(
+				"DROP TABLE IF EXISTS article_image_links",
+				"DROP TABLE IF EXISTS article_images",
+			),
+		},
+		{
+			Version: 12,
+			Name:    "add_articles_raw_json_text",
+			Up:      Exec("ALTER TABLE articles ADD COLUMN IF NOT EXISTS raw_json_text text"),
+			Down:    Exec("ALTER TABLE articles DROP COLUMN IF EXISTS raw_json_text"),
+		},
+		{
+			Version: 13,
+			Name:    "normalize_articles_newsroom_address",
+			Up:      normalizeNewsroomAddresses,
+			// The normalized addresses are kept
+			Down: Exec(),
+		},
+		{
+			Version: 14,
+			Name:    "add_article_revisions_raw_json_text",
+			Up: Exec(
+				"ALTER TABLE article_revisions ADD COLUMN IF NOT EXISTS raw_json_text text",
+				// Only the current revision of an article has its text saved
+				`UPDATE article_revisions SET raw_json_text = articles.raw_json_text
+				FROM articles
+				WHERE article_revisions.article_id = articles.id
+					AND article_revisions.revision_content_hash = articles.article_metadata->>'RevisionContentHash'
+					AND article_revisions.raw_json_text IS NULL
+					AND articles.raw_json_text IS NOT NULL`,
+			),
+			Down: Exec("ALTER TABLE article_revisions DROP COLUMN IF EXISTS raw_json_text"),
+		},
+		{
+			Version: 15,
+			Name:    "add_articles_search_body",
+			Up: Exec(
+				// The keys searched as the body are not taken from a publisher schema, the
+				// article persister SetSearchBodyKeys replaces the function to change them
+				`CREATE OR REPLACE FUNCTION articles_search_body(doc jsonb) RETURNS text AS $$
+					SELECT coalesce(doc->>'content', doc->>'body', '')
+				$$ LANGUAGE sql IMMUTABLE`,
+				`CREATE OR REPLACE FUNCTION articles_search_vector_update() RETURNS trigger AS $$
+				BEGIN
+					NEW.search_vector :=
+						setweight(to_tsvector('english', coalesce(NEW.article_metadata->>'Title', '')), 'A') ||
+						setweight(to_tsvector('english', coalesce(NEW.article_metadata->>'Description', '')), 'B') ||
+						setweight(to_tsvector('english', articles_search_body(NEW.raw_json)), 'C');
+					RETURN NEW;
+				END
+				$$ LANGUAGE plpgsql`,
+			),
+			Down: Exec(
+				`CREATE OR REPLACE FUNCTION articles_search_vector_update() RETURNS trigger AS $$
+				BEGIN
+					NEW.search_vector :=
+						setweight(to_tsvector('english', coalesce(NEW.article_metadata->>'Title', '')), 'A') ||
+						setweight(to_tsvector('english', coalesce(NEW.article_metadata->>'Description', '')), 'B') ||
+						setweight(to_tsvector('english',
+							coalesce(NEW.raw_json->>'content', NEW.raw_json->>'body', '')), 'C');
+					RETURN NEW;
+				END
+				$$ LANGUAGE plpgsql`,
+				"DROP FUNCTION IF EXISTS articles_search_body(jsonb)",
+			),
+		},
+		{
+			Version: 16,
+			Name:    "backfill_articles_raw_json_text",
+			Up: Exec(
+				// The original text of articles saved before the column existed is lost,
+				// the jsonb text is the closest to it
+				`UPDATE articles SET raw_json_text = raw_json::text
+				WHERE raw_json_text IS NULL AND raw_json IS NOT NULL`,
+				`UPDATE article_revisions SET raw_json_text = articles.raw_json_text
+				FROM articles
+				WHERE article_revisions.article_id = articles.id
+					AND article_revisions.revision_content_hash = articles.article_metadata->>'RevisionContentHash'
+					AND article_revisions.raw_json_text IS NULL
+					AND articles.raw_json_text IS NOT NULL`,
+			),
+			// The backfilled text is kept
+			Down: Exec(),
+		},
+		{
+			Version: 17,
+			Name:    "backfill_article_tags",
+			Up: Exec(
+				"INSERT INTO tags (created_at, updated_at, slug, name) "+
+					"SELECT DISTINCT ON (slug) now(), now(), slug, name "+
+					"FROM ("+unlinkedArticleTagsSQL+") AS t "+
+					"WHERE slug <> '' "+
+					"ORDER BY slug, article_id, ord "+
+					"ON CONFLICT (slug) DO NOTHING",
+				`INSERT INTO article_tags (article_id, tag_id, "primary") `+
+					"SELECT DISTINCT ON (t.article_id, tags.id) t.article_id, tags.id, t.slug = t.primary_slug "+
+					"FROM ("+unlinkedArticleTagsSQL+") AS t "+
+					"JOIN tags ON tags.slug = t.slug "+
+					"WHERE t.slug <> '' "+
+					"ORDER BY t.article_id, tags.id, t.ord "+
+					"ON CONFLICT (article_id, tag_id) DO NOTHING",
+			),
+			// The backfilled links are kept
+			Down: Exec(),
+		},
+		{
+			Version: 18,
+			Name:    "backfill_article_contributors",
+			Up: Exec(
+				"INSERT INTO contributors (created_at, updated_at, normalized_name, address, name) "+
+					"SELECT DISTINCT ON (normalized_name) now(), now(), normalized_name, '', name "+
+					"FROM ("+unlinkedArticleContributorsSQL+") AS c "+
+					"WHERE normalized_name <> '' "+
+					"ORDER BY normalized_name, article_id, ord "+
+					"ON CONFLICT (normalized_name, address) DO NOTHING",
+				"INSERT INTO article_contributors (article_id, contributor_id, role) "+
+					"SELECT DISTINCT c.article_id, contributors.id, c.role "+
+					"FROM ("+unlinkedArticleContributorsSQL+") AS c "+
+					"JOIN contributors ON contributors.normalized_name = c.normalized_name "+
+					"AND contributors.address = '' "+
+					"WHERE c.normalized_name <> '' "+
+					"ON CONFLICT (article_id, contributor_id, role) DO NOTHING",
+			),
+			// The backfilled links are kept
+			Down: Exec(),
+		},
+		{
+			Version: 19,
+			Name:    "backfill_article_images",
+			Up: Exec(
+				// Images with a hash are saved first, so images without one are matched
+				// to a hashed image with the same url like the article persister does
+				"INSERT INTO article_images (created_at, updated_at, image_key, hash, url, h, w) "+
+					"SELECT now(), now(), hash, hash, "+
+					unlinkedArticleImagesFirstSQL+
+					"FROM ("+unlinkedArticleImagesSQL+") AS i "+
+					"WHERE hash <> '' "+
+					"GROUP BY hash "+
+					upsertImageConflictSQL,
+				"INSERT INTO article_images (created_at, updated_at, image_key, hash, url, h, w) "+
+					"SELECT now(), now(), 'url:' || url, '', "+
+					unlinkedArticleImagesFirstSQL+
+					"FROM ("+unlinkedArticleImagesSQL+") AS i "+
+					"WHERE hash = '' AND url <> '' "+
+					"AND NOT EXISTS (SELECT 1 FROM article_images WHERE article_images.url = i.url "+
+					"AND article_images.hash <> '') "+
+					"GROUP BY url "+
+					upsertImageConflictSQL,
+				"INSERT INTO article_image_links (article_id, image_id, position) "+
+					"SELECT DISTINCT ON (article_id, image_id) article_id, image_id, position "+
+					"FROM (SELECT i.article_id, i.ord - 1 AS position, COALESCE("+
+					"CASE WHEN i.hash = '' THEN (SELECT min(id) FROM article_images "+
+					"WHERE article_images.url = i.url AND article_images.hash <> '') END, "+
+					"(SELECT id FROM article_images WHERE image_key = "+
+					"CASE WHEN i.hash <> '' THEN i.hash ELSE 'url:' || i.url END)) AS image_id "+
+					"FROM ("+unlinkedArticleImagesSQL+") AS i "+
+					"WHERE i.hash <> '' OR i.url <> '') AS l "+
+					"WHERE image_id IS NOT NULL "+
+					"ORDER BY article_id, image_id, position "+
+					"ON CONFLICT (article_id, image_id) DO NOTHING",
+			),
+			// The backfilled images and links are kept
+			Down: Exec(),
+		},
+	}
+}
+
+// The link backfills read the tags, contributors and images from the metadata of articles,
+// including deleted ones, that have none linked yet, and normalize them like the article
+// persister does when it saves an article. The ordinality is the position in the metadata,
+// so the first article and position a name is seen with wins, as it does when articles
+// are saved in order.
+
+// unlinkedArticleTagsSQL selects the primary tag and tags of unlinked articles with their
+// slugs, lowercased with runs of anything other than letters and digits replaced by a
+// dash as in article.NormalizeTag
+const unlinkedArticleTagsSQL = `
+SELECT articles.id AS article_id, t.ord,
+	regexp_replace(t.tag, '^\s+|\s+$', '', 'g') AS name,
+	btrim(regexp_replace(lower(t.tag), '[^[:alnum:]]+', '-', 'g'), '-') AS slug,
+	btrim(regexp_replace(lower(COALESCE(articles.article_metadata->>'PrimaryTag', '')),
+		'[^[:alnum:]]+', '-', 'g'), '-') AS primary_slug
+FROM articles, jsonb_array_elements_text(
+	CASE WHEN COALESCE(articles.article_metadata->>'PrimaryTag', '') <> ''
+		THEN jsonb_build_array(articles.article_metadata->>'PrimaryTag')
+		ELSE '[]'::jsonb
+	END ||
+	CASE WHEN jsonb_typeof(articles.article_metadata->'Tags') = 'array'
+		THEN articles.article_metadata->'Tags'
+		ELSE '[]'::jsonb
+	END
+) WITH ORDINALITY AS t(tag, ord)
+WHERE NOT EXISTS (SELECT 1 FROM article_tags WHERE article_tags.article_id = articles.id)`
+
+// unlinkedArticleContributorsSQL selects the contributors of unlinked articles with their
+// names lowercased and runs of whitespace collapsed as in article.NormalizeContributorName
+const unlinkedArticleContributorsSQL = `
+SELECT articles.id AS article_id, c.ord,
+	regexp_replace(COALESCE(c.elem->>'Name', ''), '^\s+|\s+$', '', 'g') AS name,
+	COALESCE(c.elem->>'Role', '') AS role,
+	btrim(regexp_replace(lower(COALESCE(c.elem->>'Name', '')), '\s+', ' ', 'g')) AS normalized_name
+FROM articles, jsonb_array_elements(
+	CASE WHEN jsonb_typeof(articles.article_metadata->'Contributors') = 'array'
+		THEN articles.article_metadata->'Contributors'
+		ELSE '[]'::jsonb
+	END
+) WITH ORDINALITY AS c(elem, ord)
+WHERE NOT EXISTS (SELECT 1 FROM article_contributors WHERE article_contributors.article_id = articles.id)`
+
+// unlinkedArticleImagesSQL selects the images of unlinked articles
+const unlinkedArticleImagesSQL = `
+SELECT articles.id AS article_id, i.ord,
+	COALESCE(i.elem->>'Hash', '') AS hash,
+	COALESCE(i.elem->>'URL', '') AS url,
+	COALESCE((i.elem->>'H')::integer, 0) AS h,
+	COALESCE((i.elem->>'W')::integer, 0) AS w
+FROM articles, jsonb_array_elements(
+	CASE WHEN jsonb_typeof(articles.article_metadata->'Images') = 'array'
+		THEN articles.article_metadata->'Images'
+		ELSE '[]'::jsonb
+	END
+) WITH ORDINALITY AS i(elem, ord)
+WHERE NOT EXISTS (SELECT 1 FROM article_image_links WHERE article_image_links.article_id = articles.id)`
+
+// unlinkedArticleImagesFirstSQL selects the first url and dimensions that are set for an
+// image, as the article persister fills them in when they are missing
+const unlinkedArticleImagesFirstSQL = `
+	COALESCE((array_agg(url ORDER BY article_id, ord) FILTER (WHERE url <> ''))[1], ''),
+	COALESCE((array_agg(h ORDER BY article_id, ord) FILTER (WHERE h <> 0))[1], 0),
+	COALESCE((array_agg(w ORDER BY article_id, ord) FILTER (WHERE w <> 0))[1], 0) `
+
+// upsertImageConflictSQL fills in the url and dimensions of an existing image if they are
+// missing, as the article persister does
+const upsertImageConflictSQL = `
+ON CONFLICT (image_key) DO UPDATE SET
+	updated_at = EXCLUDED.updated_at,
+	url = CASE WHEN article_images.url = '' THEN EXCLUDED.url ELSE article_images.url END,
+	h = CASE WHEN article_images.h = 0 THEN EXCLUDED.h ELSE article_images.h END,
+	w = CASE WHEN article_images.w = 0 THEN EXCLUDED.w ELSE article_images.w END`
+
+// normalizeNewsroomAddresses saves the newsroom addresses of existing articles and
+// revisions checksum cased, as the persisters save new ones. The checksum is computed in
+// go, so each address that changes is logged.
+func normalizeNewsroomAddresses(tx *gorm.DB) error {
+	for _, table := range []string{"articles", "article_revisions"} {
+		addresses := []string{}
+		err := tx.Table(table).
+			Where("COALESCE(newsroom_address, '') <> ''").
+			Pluck("DISTINCT newsroom_address", &addresses).Error
+		if err != nil {
+			return errors.Wrapf(err, "error reading the newsroom addresses of %v", table)
+		}
+
+		for _, address := range addresses {
+			normalized := ceth.NormalizeEthAddress(address)
+			if normalized == address {
+				continue
+			}
+			result := tx.Exec(
+				"UPDATE "+table+" SET newsroom_address = ? WHERE newsroom_address = ?",
+				normalized,
+				address,
+			)
+			if result.Error != nil {
+				return errors.Wrapf(result.Error, "error normalizing %v in %v", address, table)
+			}
+			log.Infof("Normalized newsroom address %v to %v on %v rows of %v",
+				address, normalized, result.RowsAffected, table)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package newsroom
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/joincivil/go-common-priv/pkg/models/article"
+	gormutils "github.com/joincivil/go-common-priv/pkg/utils/gorm"
+	carticle "github.com/joincivil/go-common/pkg/article"
+)
+
+// ContextPersister is the Persister interface with a context on every method. Cancelling
+// the context aborts the query in flight, and a passed deadline returns a
+// gormutils.TimeoutError.
+type ContextPersister interface {
+	CreateNewsroomContext(ctx context.Context, newsroom *Newsroom) error
+	UpdateNewsroomContext(ctx context.Context, newsroom *Newsroom) error
+	AddArticleContext(ctx context.Context, newsroomID uint, article *carticle.Article) error
+	NewsroomsContext(ctx context.Context) ([]*Newsroom, error)
+	NewsroomByIDContext(ctx context.Context, newsroomID uint) (*Newsroom, error)
+	NewsroomByAddressContext(ctx context.Context, addr string) (*Newsroom, error)
+	GetArticlesForNewsroomContext(ctx context.Context, newsroomID uint) ([]carticle.Article, error)
+	GetArticlesForNewsroomIndexedSinceDateContext(ctx context.Context, newsroomID uint,
+		date time.Time) ([]carticle.Article, error)
+	GetLatestArticleForNewsroomContext(ctx context.Context, newsroomID uint) (*carticle.Article, error)
+	IterateArticlesForNewsroomContext(ctx context.Context, newsroomID uint,
+		batchSize int) (article.Iterator, error)
+}
+
+// WithContext runs fn with a copy of the persister whose queries run in a transaction
+// bound to the context. The transaction is committed if fn returns nil and rolled back
+// otherwise. Errors are returned as is, use gormutils.ContextError to map them.
+func (p *GormPGPersister) WithContext(ctx context.Context, fn func(p *GormPGPersister) error) error {
+	return gormutils.WithContext(ctx, p.DB, func(tx *gorm.DB) error {
+		ctxPersister := *p
+		ctxPersister.DB = tx
+		return fn(&ctxPersister)
+	})
+}
+
+// CreateNewsroomContext is CreateNewsroom with a context
+func (p *GormPGPersister) CreateNewsroomContext(ctx context.Context, newsroom *Newsroom) error {
+	return gormutils.ContextError(ctx, p.WithContext(ctx, func(p *GormPGPersister) error {
+		return p.CreateNewsroom(newsroom)
+	}))
+}
+
+// UpdateNewsroomContext is UpdateNewsroom with a context
+func (p *GormPGPersister) UpdateNewsroomContext(ctx context.Context, newsroom *Newsroom) error {
+	return gormutils.ContextError(ctx, p.WithContext(ctx, func(p *GormPGPersister) error {
+		return p.UpdateNewsroom(newsroom)
+	}))
+}
+
+// AddArticleContext is AddArticle with a context
+func (p *GormPGPersister) AddArticleContext(ctx context.Context, newsroomID uint,
+	newArticle *carticle.Article) error {
+	return gormutils.ContextError(ctx, p.WithContext(ctx, func(p *GormPGPersister) error {
+		return p.AddArticle(newsroomID, newArticle)
+	}))
+}
+
+// NewsroomsContext is Newsrooms with a context
+func (p *GormPGPersister) NewsroomsContext(ctx context.Context) ([]*Newsroom, error) {
+	var newsrooms []*Newsroom
+	err := p.WithContext(ctx, func(p *GormPGPersister) error {
+		var err error
+		newsrooms, err = p.Newsrooms()
+		return err
+	})
+	return newsrooms, gormutils.ContextError(ctx, err)
+}
+
+// NewsroomByIDContext is NewsroomByID with a context
+func (p *GormPGPersister) NewsroomByIDContext(ctx context.Context, newsroomID uint) (*Newsroom, error) {
+	var newsroom *Newsroom
+	err := p.WithContext(ctx, func(p *GormPGPersister) error {
+		var err error
+		newsroom, err = p.NewsroomByID(newsroomID)
+		return err
+	})
+	return newsroom, gormutils.ContextError(ctx, err)
+}
+
+// NewsroomByAddressContext is NewsroomByAddress with a context
+func (p *GormPGPersister) NewsroomByAddressContext(ctx context.Context, addr string) (*Newsroom, error) {
+	var newsroom *Newsroom
+	err := p.WithContext(ctx, func(p *GormPGPersister) error {
+		var err error
+		newsroom, err = p.NewsroomByAddress(addr)
+		return err
+	})
+	return newsroom, gormutils.ContextError(ctx, err)
+}
+
+// GetArticlesForNewsroomContext is GetArticlesForNewsroom with a context
+func (p *GormPGPersister) GetArticlesForNewsroomContext(ctx context.Context,
+	newsroomID uint) ([]carticle.Article, error) {
+	var articles []carticle.Article
+	err := p.WithContext(ctx, func(p *GormPGPersister) error {
+		var err error
+		articles, err = p.GetArticlesForNewsroom(newsroomID)
+		return err
+	})
+	return articles, gormutils.ContextError(ctx, err)
+}
+
+// GetArticlesForNewsroomIndexedSinceDateContext is GetArticlesForNewsroomIndexedSinceDate
+// with a context
+func (p *GormPGPersister) GetArticlesForNewsroomIndexedSinceDateContext(ctx context.Context,
+	newsroomID uint, date time.Time) ([]carticle.Article, error) {
+	var articles []carticle.Article
+	err := p.WithContext(ctx, func(p *GormPGPersister) error {
+		var err error
+		articles, err = p.GetArticlesForNewsroomIndexedSinceDate(newsroomID, date)
+		return err
+	})
+	return articles, gormutils.ContextError(ctx, err)
+}
+
+// GetLatestArticleForNewsroomContext is GetLatestArticleForNewsroom with a context
+func (p *GormPGPersister) GetLatestArticleForNewsroomContext(ctx context.Context,
+	newsroomID uint) (*carticle.Article, error) {
+	var latest *carticle.Article
+	err := p.WithContext(ctx, func(p *GormPGPersister) error {
+		var err error
+		latest, err = p.GetLatestArticleForNewsroom(newsroomID)
+		return err
+	})
+	return latest, gormutils.ContextError(ctx, err)
+}
+
+// IterateArticlesForNewsroomContext is IterateArticlesForNewsroom with a context. The
+// iteration stops with the context error once the context is done.
+func (p *GormPGPersister) IterateArticlesForNewsroomContext(ctx context.Context, newsroomID uint,
+	batchSize int) (article.Iterator, error) {
+	newsroomGorm := Gorm{}
+	err := p.WithContext(ctx, func(p *GormPGPersister) error {
+		return p.DB.First(&newsroomGorm, newsroomID).Error
+	})
+	if err != nil {
+		return nil, gormutils.ContextError(ctx, err)
+	}
+
+	articlePersister := &article.GormPGPersister{DB: p.DB}
+	return articlePersister.IterateArticlesContext(
+		ctx,
+		&article.ArticleFilter{NewsroomAddress: newsroomGorm.Address},
+		batchSize,
+	), nil
+}
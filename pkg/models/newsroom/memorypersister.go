@@ -0,0 +1,291 @@
+package newsroom
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/golang/glog"
+
+	"github.com/jinzhu/gorm"
+	"github.com/jinzhu/gorm/dialects/postgres"
+	"github.com/pkg/errors"
+
+	"github.com/joincivil/go-common-priv/pkg/models/article"
+	carticle "github.com/joincivil/go-common/pkg/article"
+	ceth "github.com/joincivil/go-common/pkg/eth"
+)
+
+var (
+	// ErrDuplicateAddress indicates that a newsroom with the address already exists
+	ErrDuplicateAddress = errors.New("newsroom address already exists")
+)
+
+// MemoryPersister is an in-memory implementation of Persister for tests. It follows
+// GormPGPersister: addresses are normalized, lookups that find nothing return
+// gorm.ErrRecordNotFound and GetLatestArticleForNewsroom returns ErrNoArticles.
+// It is safe for concurrent use.
+type MemoryPersister struct {
+	// Articles stores the articles of the newsrooms
+	Articles *article.MemoryPersister
+
+	mu        sync.RWMutex
+	newsrooms map[uint]*Gorm
+	lastID    uint
+}
+
+// NewMemoryPersister returns an empty MemoryPersister that stores the articles of the
+// newsrooms in articles, or in a new article.MemoryPersister if articles is nil
+func NewMemoryPersister(articles *article.MemoryPersister) *MemoryPersister {
+	if articles == nil {
+		articles = article.NewMemoryPersister()
+	}
+	return &MemoryPersister{Articles: articles, newsrooms: map[uint]*Gorm{}}
+}
+
+// CreateNewsroom takes a newsroom struct and saves it
+func (p *MemoryPersister) CreateNewsroom(newsroom *Newsroom) error {
+	bys, err := json.Marshal(newsroom.Meta)
+	if err != nil {
+		return errors.Wrap(err, "error marshalling metadata")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	address := ceth.NormalizeEthAddress(newsroom.Address)
+	if p.hasAddress(address, 0) {
+		return ErrDuplicateAddress
+	}
+
+	p.lastID++
+	now := gorm.NowFunc()
+	newsroomGorm := &Gorm{
+		Name:    newsroom.Name,
+		Address: address,
+		Meta:    postgres.Jsonb{RawMessage: bys},
+	}
+	newsroomGorm.ID = p.lastID
+	newsroomGorm.CreatedAt = now
+	newsroomGorm.UpdatedAt = now
+	p.newsrooms[newsroomGorm.ID] = newsroomGorm
+
+	newsroom.ID = newsroomGorm.ID
+	return nil
+}
+
+// UpdateNewsroom takes a newsroom struct that has an id and updates it with new values
+func (p *MemoryPersister) UpdateNewsroom(newsroom *Newsroom) error {
+	bys, err := json.Marshal(newsroom.Meta)
+	if err != nil {
+		return errors.Wrap(err, "error marshalling metadata")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	newsroomGorm, ok := p.newsrooms[newsroom.ID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+
+	address := ceth.NormalizeEthAddress(newsroom.Address)
+	if p.hasAddress(address, newsroom.ID) {
+		return ErrDuplicateAddress
+	}
+
+	newsroomGorm.Name = newsroom.Name
+	newsroomGorm.Address = address
+	newsroomGorm.Meta = postgres.Jsonb{RawMessage: bys}
+	newsroomGorm.UpdatedAt = gorm.NowFunc()
+	return nil
+}
+
+func (p *MemoryPersister) hasAddress(address string, exceptID uint) bool {
+	for id, newsroomGorm := range p.newsrooms {
+		if id != exceptID && newsroomGorm.Address == address {
+			return true
+		}
+	}
+	return false
+}
+
+// AddArticle adds an article to a newsroom with the given ID. As with GormPGPersister,
+// the ID is not set on the given article.
+func (p *MemoryPersister) AddArticle(newsroomID uint, newArticle *carticle.Article) error {
+	articleGorm := article.Gorm{}
+	if err := articleGorm.PopulateFromArticle(newArticle); err != nil {
+		return err
+	}
+
+	newsroomGorm, err := p.newsroomGorm(newsroomID)
+	if err != nil {
+		return err
+	}
+
+	articleGorm.NewsroomAddress = newsroomGorm.Address
+	p.Articles.SaveArticleGorm(&articleGorm)
+	return nil
+}
+
+// Newsrooms returns the list of newsrooms, ordered by ID
+func (p *MemoryPersister) Newsrooms() ([]*Newsroom, error) {
+	p.mu.RLock()
+	newsroomGorms := make([]Gorm, 0, len(p.newsrooms))
+	for _, newsroomGorm := range p.newsrooms {
+		newsroomGorms = append(newsroomGorms, *newsroomGorm)
+	}
+	p.mu.RUnlock()
+
+	sort.Slice(newsroomGorms, func(i, j int) bool {
+		return newsroomGorms[i].ID < newsroomGorms[j].ID
+	})
+
+	newsrooms := make([]*Newsroom, len(newsroomGorms))
+	for ind := range newsroomGorms {
+		newsroom, err := convertNewsroomGorm(&newsroomGorms[ind])
+		if err != nil {
+			log.Errorf("error unmarshalling meta: err: %v", err)
+			continue
+		}
+		newsrooms[ind] = newsroom
+	}
+
+	return newsrooms, nil
+}
+
+// NewsroomByID returns the newsroom with the given ID if its found
+func (p *MemoryPersister) NewsroomByID(newsroomID uint) (*Newsroom, error) {
+	newsroomGorm, err := p.newsroomGorm(newsroomID)
+	if err != nil {
+		return nil, err
+	}
+	return convertNewsroomGorm(newsroomGorm)
+}
+
+// NewsroomByAddress returns the newsroom with the given eth address if its found
+func (p *MemoryPersister) NewsroomByAddress(addr string) (*Newsroom, error) {
+	normalizedAddr := ceth.NormalizeEthAddress(addr)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, newsroomGorm := range p.newsrooms {
+		if newsroomGorm.Address == normalizedAddr {
+			return convertNewsroomGorm(newsroomGorm)
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// GetArticlesForNewsroom returns all the articles for a newsroom with the given ID
+func (p *MemoryPersister) GetArticlesForNewsroom(newsroomID uint) ([]carticle.Article, error) {
+	return p.articlesForNewsroom(newsroomID, &article.ArticleFilter{})
+}
+
+// GetArticlesForNewsroomIndexedSinceDate returns all articles for a newsroom indexed after the date
+func (p *MemoryPersister) GetArticlesForNewsroomIndexedSinceDate(newsroomID uint, date time.Time) ([]carticle.Article, error) {
+	return p.articlesForNewsroom(newsroomID, &article.ArticleFilter{IndexedAfter: date})
+}
+
+// GetLatestArticleForNewsroom returns the latest article for a newsroom with the given ID.
+// Like GormPGPersister, articles are ordered by the text of their OriginalPublishDate as
+// stored in the metadata json.
+func (p *MemoryPersister) GetLatestArticleForNewsroom(newsroomID uint) (*carticle.Article, error) {
+	articles, err := p.GetArticlesForNewsroom(newsroomID)
+	if err != nil {
+		return nil, err
+	}
+	if len(articles) == 0 {
+		return nil, ErrNoArticles
+	}
+
+	latest := 0
+	latestDate := publishDateText(&articles[0])
+	for i := 1; i < len(articles); i++ {
+		if date := publishDateText(&articles[i]); date > latestDate {
+			latest = i
+			latestDate = date
+		}
+	}
+	return &articles[latest], nil
+}
+
+// IterateArticlesForNewsroom returns an iterator over the articles for a newsroom with the
+// given ID, most recently indexed first
+func (p *MemoryPersister) IterateArticlesForNewsroom(newsroomID uint, batchSize int) (article.Iterator, error) {
+	newsroomGorm, err := p.newsroomGorm(newsroomID)
+	if err != nil {
+		return nil, err
+	}
+	return p.Articles.IterateArticles(
+		&article.ArticleFilter{NewsroomAddress: newsroomGorm.Address},
+		batchSize,
+	), nil
+}
+
+// articlesForNewsroom returns all the articles of the newsroom matching the filter,
+// ordered by ID
+func (p *MemoryPersister) articlesForNewsroom(newsroomID uint,
+	filter *article.ArticleFilter) ([]carticle.Article, error) {
+	newsroomGorm, err := p.newsroomGorm(newsroomID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter.NewsroomAddress = newsroomGorm.Address
+	articles := []carticle.Article{}
+	it := p.Articles.IterateArticles(filter, 0)
+	defer it.Close() // nolint: errcheck
+	for it.Next() {
+		articles = append(articles, *it.Article())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(articles, func(i, j int) bool { return articles[i].ID < articles[j].ID })
+	return articles, nil
+}
+
+func (p *MemoryPersister) newsroomGorm(newsroomID uint) (*Gorm, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	newsroomGorm, ok := p.newsrooms[newsroomID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *newsroomGorm
+	return &copied, nil
+}
+
+func convertNewsroomGorm(newsroomGorm *Gorm) (*Newsroom, error) {
+	newsroom := &Newsroom{}
+	newsroom.ID = newsroomGorm.ID
+	newsroom.Name = newsroomGorm.Name
+	newsroom.Address = newsroomGorm.Address
+
+	var meta *Meta
+	err := json.Unmarshal(newsroomGorm.Meta.RawMessage, &meta)
+	if err != nil {
+		return nil, errors.Wrap(err, "error unmarshalling meta")
+	}
+	newsroom.Meta = meta
+
+	return newsroom, nil
+}
+
+// publishDateText returns OriginalPublishDate as the text stored in the metadata json
+func publishDateText(a *carticle.Article) string {
+	bys, err := json.Marshal(a.ArticleMetadata.OriginalPublishDate)
+	if err != nil {
+		return ""
+	}
+	var text string
+	if err := json.Unmarshal(bys, &text); err != nil {
+		return ""
+	}
+	return text
+}
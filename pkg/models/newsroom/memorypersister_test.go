@@ -0,0 +1,118 @@
+package newsroom_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/joincivil/go-common-priv/pkg/models/newsroom"
+	"github.com/joincivil/go-common-priv/pkg/models/testutils"
+	carticle "github.com/joincivil/go-common/pkg/article"
+)
+
+func TestMemoryPersister(t *testing.T) {
+	persister := newsroom.NewMemoryPersister(nil)
+	testFunc(persister)
+
+	newsrooma := &newsroom.Newsroom{
+		Name:    "Newsroom1",
+		Address: "0x8c722b8ac728add7780a66017e8dadba530ee261",
+		Meta:    &newsroom.Meta{Index: true},
+	}
+	if err := persister.CreateNewsroom(newsrooma); err != nil {
+		t.Fatalf("should have created a newsroom: err: %v", err)
+	}
+	if err := persister.CreateNewsroom(&newsroom.Newsroom{Address: strings.ToUpper(newsrooma.Address)}); err == nil {
+		t.Errorf("should not have created a newsroom with the same address")
+	}
+
+	nr, err := persister.NewsroomByAddress(newsrooma.Address)
+	if err != nil {
+		t.Fatalf("should have found the newsroom by address: err: %v", err)
+	}
+	if nr.Address != "0x8c722B8AC728aDd7780a66017e8daDBa530EE261" {
+		t.Errorf("should have normalized the address: %v", nr.Address)
+	}
+	if nr.Meta == nil || !nr.Meta.Index {
+		t.Errorf("should have round tripped the meta: %v", nr.Meta)
+	}
+
+	if _, err := persister.NewsroomByID(newsrooma.ID + 1); err != gorm.ErrRecordNotFound {
+		t.Errorf("should have returned gorm.ErrRecordNotFound: err: %v", err)
+	}
+
+	if _, err := persister.GetLatestArticleForNewsroom(newsrooma.ID); err != newsroom.ErrNoArticles {
+		t.Errorf("should have returned ErrNoArticles: err: %v", err)
+	}
+
+	now := time.Now()
+	publishDates := []time.Time{
+		now.Add(-2 * time.Hour),
+		now,
+		now.Add(-1 * time.Hour),
+	}
+	for i, publishDate := range publishDates {
+		narticle := &carticle.Article{
+			ArticleMetadata: carticle.Metadata{
+				Title:               []string{"old", "latest", "mid"}[i],
+				OriginalPublishDate: publishDate,
+			},
+			IndexedTimestamp: now.Add(time.Duration(i-1) * time.Second),
+		}
+		if err := persister.AddArticle(newsrooma.ID, narticle); err != nil {
+			t.Errorf("should have added article: err: %v", err)
+		}
+	}
+
+	latest, err := persister.GetLatestArticleForNewsroom(newsrooma.ID)
+	if err != nil {
+		t.Errorf("should have found the latest article: err: %v", err)
+	} else if latest.ArticleMetadata.Title != "latest" {
+		t.Errorf("should have returned the latest published article: %v", latest.ArticleMetadata.Title)
+	}
+
+	articles, err := persister.GetArticlesForNewsroom(newsrooma.ID)
+	if err != nil {
+		t.Errorf("should have returned the articles: err: %v", err)
+	}
+	if len(articles) != 3 || articles[0].NewsroomAddress != nr.Address {
+		t.Errorf("should have added the articles to the newsroom: %v", articles)
+	}
+
+	articles, err = persister.GetArticlesForNewsroomIndexedSinceDate(newsrooma.ID, now)
+	if err != nil {
+		t.Errorf("should have returned the articles: err: %v", err)
+	}
+	if len(articles) != 2 {
+		t.Errorf("should have only returned the articles indexed since the date: %v", len(articles))
+	}
+
+	it, err := persister.IterateArticlesForNewsroom(newsrooma.ID, 2)
+	if err != nil {
+		t.Fatalf("should have returned an iterator: err: %v", err)
+	}
+	count := 0
+	for it.Next() {
+		count++
+	}
+	if it.Err() != nil || count != 3 {
+		t.Errorf("should have iterated over the articles: %v: err: %v", count, it.Err())
+	}
+
+	newsrooma.Name = "Renamed"
+	if err := persister.UpdateNewsroom(newsrooma); err != nil {
+		t.Errorf("should have updated the newsroom: err: %v", err)
+	}
+	newsrooms, err := persister.Newsrooms()
+	if err != nil || len(newsrooms) != 1 || newsrooms[0].Name != "Renamed" {
+		t.Errorf("should have returned the updated newsroom: %v: err: %v", newsrooms, err)
+	}
+}
+
+func TestMemoryPersisterSuite(t *testing.T) {
+	testutils.RunNewsroomPersisterSuite(t, func(t *testing.T) (newsroom.Persister, func()) {
+		return newsroom.NewMemoryPersister(nil), func() {}
+	})
+}
@@ -2,7 +2,6 @@ package newsroom
 
 import (
 	"encoding/json"
-	"fmt"
 	"time"
 
 	log "github.com/golang/glog"
@@ -12,6 +11,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/joincivil/go-common-priv/pkg/models/article"
+	gormutils "github.com/joincivil/go-common-priv/pkg/utils/gorm"
 	carticle "github.com/joincivil/go-common/pkg/article"
 	ceth "github.com/joincivil/go-common/pkg/eth"
 )
@@ -21,13 +21,6 @@ var (
 	ErrNoArticles = errors.New("no articles found")
 )
 
-const (
-	// Could make this configurable later if needed
-	maxOpenConns    = 5
-	maxIdleConns    = 3
-	connMaxLifetime = time.Second * 1800 // 30 mins
-)
-
 // Gorm is the newsroom schema
 type Gorm struct {
 	gorm.Model
@@ -47,19 +40,23 @@ type GormPGPersister struct {
 	DB *gorm.DB
 }
 
-// NewGormPGPersister takes information about the db and returns a newsroom persister that uses gorm and postgres
-func NewGormPGPersister(host string, port int, user string, password string, dbname string) (*GormPGPersister, error) {
+// NewGormPGPersister takes information about the db and returns a newsroom persister that uses gorm and postgres.
+// The connection pool uses the defaults of gormutils.NewOptions unless set by opts.
+func NewGormPGPersister(host string, port int, user string, password string, dbname string,
+	opts ...gormutils.Option) (*GormPGPersister, error) {
+	return NewGormPGPersisterWithConfig(gormutils.NewConfig(host, port, user, password, dbname), opts...)
+}
+
+// NewGormPGPersisterWithConfig takes the connection config of the db and returns a newsroom persister that uses gorm and postgres.
+// Use it to connect with TLS or the other options of gormutils.Config. The connection pool
+// uses the defaults of gormutils.NewOptions unless set by opts.
+func NewGormPGPersisterWithConfig(cfg *gormutils.Config, opts ...gormutils.Option) (*GormPGPersister, error) {
 	newsroomGormPGPersister := &GormPGPersister{}
-	psqlInfo := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable", host, port, user, password, dbname)
-	db, err := gorm.Open("postgres", psqlInfo)
+	db, err := gormutils.NewGormPGConnectionWithConfig(cfg, opts...)
 	if err != nil {
-		return newsroomGormPGPersister, errors.Wrap(err, "error connecting to gorm")
+		return newsroomGormPGPersister, err
 	}
 	newsroomGormPGPersister.DB = db
-
-	newsroomGormPGPersister.DB.DB().SetMaxOpenConns(maxOpenConns)
-	newsroomGormPGPersister.DB.DB().SetMaxIdleConns(maxIdleConns)
-	newsroomGormPGPersister.DB.DB().SetConnMaxLifetime(connMaxLifetime)
 	return newsroomGormPGPersister, nil
 }
 
@@ -114,7 +111,9 @@ func (p *GormPGPersister) UpdateNewsroom(newsroom *Newsroom) error {
 	return err
 }
 
-// AddArticle adds an article to a newsroom with the given ID
+// AddArticle adds an article to a newsroom with the given ID. If the opt in index from
+// article.GormPGPersister.ArticleCanonicalURLUniqueIndex exists, returns an error if the
+// newsroom already has an article with the same canonical URL.
 func (p *GormPGPersister) AddArticle(newsroomID uint, newArticle *carticle.Article) error {
 	newsroomGorm := Gorm{}
 
@@ -257,6 +256,22 @@ func (p *GormPGPersister) GetLatestArticleForNewsroom(newsroomID uint) (*carticl
 	return convertedArticle, nil
 }
 
+// IterateArticlesForNewsroom returns an iterator over the articles for a newsroom with the
+// given ID, most recently indexed first. Unlike GetArticlesForNewsroom, the articles are
+// fetched in batches of batchSize, or the default page size if batchSize is 0.
+func (p *GormPGPersister) IterateArticlesForNewsroom(newsroomID uint, batchSize int) (article.Iterator, error) {
+	newsroomGorm := Gorm{}
+	if err := p.DB.First(&newsroomGorm, newsroomID).Error; err != nil {
+		return nil, err
+	}
+
+	articlePersister := &article.GormPGPersister{DB: p.DB}
+	return articlePersister.IterateArticles(
+		&article.ArticleFilter{NewsroomAddress: newsroomGorm.Address},
+		batchSize,
+	), nil
+}
+
 func (p *GormPGPersister) convertedArticles(newsroomGorm Gorm) ([]carticle.Article, error) {
 	articles := make([]carticle.Article, len(newsroomGorm.Articles))
 	for i, a := range newsroomGorm.Articles {
@@ -1,6 +1,7 @@
 package newsroom_test
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"testing"
@@ -15,11 +16,15 @@ import (
 func testFunc(persister newsroom.Persister) {
 }
 
+func testContextFunc(persister newsroom.ContextPersister) {
+}
+
 func TestGormInterface(t *testing.T) {
 	// Ensure the GORM persister implements the Persister interface
 	creds := testutils.GetTestDBConnection()
 	pg, _ := newsroom.NewGormPGPersister(creds.Host, creds.Port, creds.User, creds.Password, creds.Dbname)
 	testFunc(pg)
+	testContextFunc(pg)
 }
 
 func TestCreateNewsroom(t *testing.T) {
@@ -522,3 +527,121 @@ func TestGetArticlesForNewsroomIndexedSinceDate(t *testing.T) {
 		t.Errorf("did not fetch all or only articles indexed after now")
 	}
 }
+
+func TestIterateArticlesForNewsroom(t *testing.T) {
+	creds := testutils.GetTestDBConnection()
+	pg, err := newsroom.NewGormPGPersister(creds.Host, creds.Port, creds.User, creds.Password, creds.Dbname)
+
+	if err != nil {
+		fmt.Println(err)
+		t.Errorf("threw an error making the persister")
+	}
+
+	testutils.MigrateModels(pg.DB) // nolint: errcheck
+
+	defer pg.DB.Close()
+
+	cleaner := testutils.DeleteCreatedEntities(pg.DB)
+	defer cleaner()
+
+	newsrooma := &newsroom.Newsroom{
+		Name:    "Newsroom1",
+		Address: "0x8c722B8AC728aDd7780a66017e8daDBa530EE261",
+	}
+
+	if err1 := pg.CreateNewsroom(newsrooma); err1 != nil {
+		t.Errorf("should have created a newsroom")
+	}
+
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		narticle := &carticle.Article{
+			ArticleMetadata: carticle.Metadata{
+				Title:        fmt.Sprintf("iterated stuff %d", i),
+				CanonicalURL: fmt.Sprintf("https://newstuff.bz/iteratedarticle%d", i),
+			},
+			NewsroomAddress:  "0x8c722B8AC728aDd7780a66017e8daDBa530EE261",
+			IndexedTimestamp: now.Add(time.Duration(i) * time.Second),
+		}
+		if err1 := pg.AddArticle(newsrooma.ID, narticle); err1 != nil {
+			t.Errorf("failed to add article")
+		}
+	}
+
+	it, err := pg.IterateArticlesForNewsroom(newsrooma.ID, 2)
+	if err != nil {
+		t.Fatalf("should not have returned error: err: %v", err)
+	}
+	defer it.Close() // nolint: errcheck
+
+	titles := []string{}
+	for it.Next() {
+		titles = append(titles, it.Article().ArticleMetadata.Title)
+	}
+	if it.Err() != nil {
+		t.Errorf("should not have returned error iterating: err: %v", it.Err())
+	}
+	if len(titles) != 5 || titles[0] != "iterated stuff 4" || titles[4] != "iterated stuff 0" {
+		t.Errorf("should have iterated over all articles, most recently indexed first: %v", titles)
+	}
+
+	if _, err := pg.IterateArticlesForNewsroom(newsrooma.ID+1000, 2); err == nil {
+		t.Errorf("should have returned error for an unknown newsroom")
+	}
+}
+
+func TestNewsroomContextMethods(t *testing.T) {
+	creds := testutils.GetTestDBConnection()
+	pg, err := newsroom.NewGormPGPersister(creds.Host, creds.Port, creds.User, creds.Password, creds.Dbname)
+
+	if err != nil {
+		fmt.Println(err)
+		t.Errorf("threw an error making the persister")
+	}
+
+	testutils.MigrateModels(pg.DB) // nolint: errcheck
+
+	defer pg.DB.Close()
+
+	cleaner := testutils.DeleteCreatedEntities(pg.DB)
+	defer cleaner()
+
+	newsrooma := &newsroom.Newsroom{
+		Name:    "Newsroom1",
+		Address: "0x8c722B8AC728aDd7780a66017e8daDBa530EE261",
+		Meta:    &newsroom.Meta{Index: true},
+	}
+	if err1 := pg.CreateNewsroom(newsrooma); err1 != nil {
+		t.Errorf("should have created a newsroom")
+	}
+
+	nr, err := pg.NewsroomByAddressContext(context.Background(), strings.ToLower(newsrooma.Address))
+	if err != nil {
+		t.Errorf("should have found the newsroom: err: %v", err)
+	} else if nr.ID != newsrooma.ID {
+		t.Errorf("should have found the newsroom by its normalized address")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := pg.NewsroomByIDContext(ctx, newsrooma.ID); err != context.Canceled {
+		t.Errorf("should have returned context.Canceled: err: %v", err)
+	}
+}
+
+func TestGormPGPersisterSuite(t *testing.T) {
+	testutils.RunNewsroomPersisterSuite(t, func(t *testing.T) (newsroom.Persister, func()) {
+		creds := testutils.GetTestDBConnection()
+		pg, err := newsroom.NewGormPGPersister(creds.Host, creds.Port, creds.User, creds.Password, creds.Dbname)
+		if err != nil {
+			t.Fatalf("should have connected to the db: err: %v", err)
+		}
+		testutils.MigrateModels(pg.DB) // nolint: errcheck
+
+		cleaner := testutils.DeleteCreatedEntities(pg.DB)
+		return pg, func() {
+			cleaner()
+			pg.DB.Close() // nolint: errcheck
+		}
+	})
+}
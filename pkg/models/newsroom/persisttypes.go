@@ -3,6 +3,7 @@ package newsroom
 import (
 	"time"
 
+	"github.com/joincivil/go-common-priv/pkg/models/article"
 	carticle "github.com/joincivil/go-common/pkg/article"
 )
 
@@ -35,4 +36,5 @@ type Persister interface {
 	GetArticlesForNewsroom(newsroomID uint) ([]carticle.Article, error)
 	GetArticlesForNewsroomIndexedSinceDate(newsroomID uint, date time.Time) ([]carticle.Article, error)
 	GetLatestArticleForNewsroom(newsroomID uint) (*carticle.Article, error)
+	IterateArticlesForNewsroom(newsroomID uint, batchSize int) (article.Iterator, error)
 }
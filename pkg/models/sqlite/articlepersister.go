@@ -0,0 +1,418 @@
+package sqlite
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/jinzhu/gorm/dialects/postgres"
+	"github.com/pkg/errors"
+
+	"github.com/joincivil/go-common-priv/pkg/models/article"
+	"github.com/joincivil/go-common-priv/pkg/models/internal/listcursor"
+	gormutils "github.com/joincivil/go-common-priv/pkg/utils/gorm"
+	carticle "github.com/joincivil/go-common/pkg/article"
+)
+
+// ArticleGorm is the article schema in sqlite. It has the columns of article.Gorm with
+// the json stored as text.
+type ArticleGorm struct {
+	gorm.Model
+	BlockData        JSONText
+	ArticleMetadata  JSONText
+	NewsroomAddress  string `gorm:"index"`
+	IndexedTimestamp time.Time
+	RawJSON          JSONText `gorm:"column:raw_json"`
+	// TxHash, BlockNumber, BlockHash and TxStatus are copied from BlockData
+	TxHash      string `gorm:"index"`
+	BlockNumber uint64 `gorm:"index"`
+	BlockHash   string
+	TxStatus    uint64
+	// AnchorState is where the article is in the content claim pipeline
+	AnchorState article.AnchorState `gorm:"index;default:'pending'"`
+}
+
+// TableName sets the name of the corresponding table in the db
+func (ArticleGorm) TableName() string {
+	return "articles"
+}
+
+// ConvertToArticle returns the gorm struct as the public article struct. It converts
+// the same way as article.Gorm.
+func (a *ArticleGorm) ConvertToArticle() (*carticle.Article, error) {
+	articleGorm := &article.Gorm{
+		Model:            a.Model,
+		BlockData:        postgres.Jsonb{RawMessage: json.RawMessage(a.BlockData)},
+		ArticleMetadata:  postgres.Jsonb{RawMessage: json.RawMessage(a.ArticleMetadata)},
+		NewsroomAddress:  a.NewsroomAddress,
+		IndexedTimestamp: a.IndexedTimestamp,
+		RawJSON:          postgres.Jsonb{RawMessage: json.RawMessage(a.RawJSON)},
+		TxHash:           a.TxHash,
+		BlockNumber:      a.BlockNumber,
+		BlockHash:        a.BlockHash,
+		TxStatus:         a.TxStatus,
+		AnchorState:      a.AnchorState,
+	}
+	return articleGorm.ConvertToArticle()
+}
+
+// PopulateFromArticle takes an article struct and maps its properties onto a gorm struct.
+// It maps the same way as article.Gorm, with the indexed timestamp in UTC so timestamps
+// stored as text compare in order.
+func (a *ArticleGorm) PopulateFromArticle(art *carticle.Article) error {
+	articleGorm := &article.Gorm{}
+	if err := articleGorm.PopulateFromArticle(art); err != nil {
+		return err
+	}
+
+	a.ID = articleGorm.ID
+	a.BlockData = JSONText(articleGorm.BlockData.RawMessage)
+	a.ArticleMetadata = JSONText(articleGorm.ArticleMetadata.RawMessage)
+	a.NewsroomAddress = articleGorm.NewsroomAddress
+	a.IndexedTimestamp = articleGorm.IndexedTimestamp.UTC()
+	a.RawJSON = JSONText(articleGorm.RawJSON.RawMessage)
+	a.TxHash = articleGorm.TxHash
+	a.BlockNumber = articleGorm.BlockNumber
+	a.BlockHash = articleGorm.BlockHash
+	a.TxStatus = articleGorm.TxStatus
+	return nil
+}
+
+// ArticlePersister implements article.Persister on sqlite
+type ArticlePersister struct {
+	DB *gorm.DB
+	// RequireContentHashMatch fails creates and updates of articles whose raw json
+	// does not match their RevisionContentHash
+	RequireContentHashMatch bool
+}
+
+// NewArticlePersister opens the sqlite database at path and returns an article persister
+// that uses it. See Open.
+func NewArticlePersister(path string) (*ArticlePersister, error) {
+	db, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &ArticlePersister{DB: db}, nil
+}
+
+// NewArticlePersisterWithDB uses an existing gorm.DB struct to create a new ArticlePersister.
+// This is useful to share the database with a NewsroomPersister.
+func NewArticlePersisterWithDB(db *gorm.DB) (*ArticlePersister, error) {
+	return &ArticlePersister{DB: db}, nil
+}
+
+// ArticleByID finds an article by its ID
+func (p *ArticlePersister) ArticleByID(articleID uint, opts ...article.LookupOption) (*carticle.Article, error) {
+	articleGorm := &ArticleGorm{}
+	if err := lookupDB(p.DB, opts).First(articleGorm, articleID).Error; err != nil {
+		return nil, err
+	}
+
+	return articleGorm.ConvertToArticle()
+}
+
+// ArticleByCanonicalURL finds the most recently created article with the given canonical URL.
+// Returns article.ErrArticleNotFound if there is no match.
+func (p *ArticlePersister) ArticleByCanonicalURL(canonicalURL string,
+	opts ...article.LookupOption) (*carticle.Article, error) {
+	return p.articleByMetadataField("CanonicalURL", canonicalURL, opts)
+}
+
+// ArticleByRevisionContentHash finds the most recently created article with the given
+// revision content hash. Returns article.ErrArticleNotFound if there is no match.
+func (p *ArticlePersister) ArticleByRevisionContentHash(contentHash string,
+	opts ...article.LookupOption) (*carticle.Article, error) {
+	return p.articleByMetadataField("RevisionContentHash", contentHash, opts)
+}
+
+func (p *ArticlePersister) articleByMetadataField(field string, value string,
+	opts []article.LookupOption) (*carticle.Article, error) {
+	articleGorm := &ArticleGorm{}
+	err := lookupDB(p.DB, opts).
+		Where(metadataField(field)+" = ?", value).
+		Order("id DESC").
+		First(articleGorm).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, article.ErrArticleNotFound
+		}
+		return nil, err
+	}
+
+	return articleGorm.ConvertToArticle()
+}
+
+// CreateArticle saves an article and sets its ID. Block data is only saved by updates,
+// as in article.GormPGPersister.
+func (p *ArticlePersister) CreateArticle(art *carticle.Article) error {
+	if err := p.verifyContentHash(art); err != nil {
+		return err
+	}
+
+	metaJSON, err := json.Marshal(art.ArticleMetadata)
+	if err != nil {
+		return err
+	}
+
+	articleGorm := &ArticleGorm{
+		NewsroomAddress:  article.NormalizeNewsroomAddress(art.NewsroomAddress),
+		ArticleMetadata:  JSONText(metaJSON),
+		IndexedTimestamp: art.IndexedTimestamp.UTC(),
+		RawJSON:          JSONText(art.RawJSON),
+		AnchorState:      article.AnchorStatePending,
+	}
+	if err := p.DB.Create(articleGorm).Error; err != nil {
+		return err
+	}
+
+	art.ID = articleGorm.ID
+	return nil
+}
+
+// UpdateArticle saves updates to an article. The anchor state only changes through
+// transitions, an article with block data is moved to confirmed. Returns
+// article.ErrArticleNotFound if the article is soft deleted.
+func (p *ArticlePersister) UpdateArticle(art *carticle.Article) error {
+	if err := p.verifyContentHash(art); err != nil {
+		return err
+	}
+
+	articleGorm := &ArticleGorm{}
+	if err := articleGorm.PopulateFromArticle(art); err != nil {
+		return err
+	}
+
+	return gormutils.WithTxOptions(p.DB, txOptions, func(tx *gorm.DB) error {
+		if err := checkNotDeleted(tx, articleGorm.ID); err != nil {
+			return err
+		}
+		if err := tx.Omit("anchor_state").Save(articleGorm).Error; err != nil {
+			return err
+		}
+		if articleGorm.TxHash == "" {
+			return nil
+		}
+		return confirmAnchorState(tx, articleGorm.ID)
+	})
+}
+
+// checkNotDeleted returns article.ErrArticleNotFound if the article is soft deleted, as in
+// article.GormPGPersister
+func checkNotDeleted(tx *gorm.DB, articleID uint) error {
+	if articleID == 0 {
+		return nil
+	}
+	count := 0
+	err := tx.Unscoped().Model(&ArticleGorm{}).
+		Where("id = ? AND deleted_at IS NOT NULL", articleID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return article.ErrArticleNotFound
+	}
+	return nil
+}
+
+func (p *ArticlePersister) verifyContentHash(art *carticle.Article) error {
+	if !p.RequireContentHashMatch {
+		return nil
+	}
+	return article.VerifyContentHash(art)
+}
+
+// ListArticles returns a page of articles matching the filter, ordered by most recently
+// indexed first, as in article.GormPGPersister. Raw json queries are not supported.
+func (p *ArticlePersister) ListArticles(filter *article.ArticleFilter,
+	page *article.PageRequest) (*article.ArticleListing, error) {
+	limit := listcursor.Limit(0)
+	if page != nil {
+		limit = listcursor.Limit(page.Limit)
+	}
+
+	db, err := applyArticleFilter(p.DB.Model(&ArticleGorm{}), filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if page != nil && page.Cursor != "" {
+		cursor, err := listcursor.Decode(page.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where(
+			"(articles.indexed_timestamp, articles.id) < (?, ?)",
+			cursor.IndexedTimestamp.UTC(),
+			cursor.ID,
+		)
+	}
+
+	articleGorms := []ArticleGorm{}
+	err = db.Order("articles.indexed_timestamp DESC, articles.id DESC").
+		Limit(limit + 1).
+		Find(&articleGorms).Error
+	if err != nil {
+		return nil, err
+	}
+
+	listing := &article.ArticleListing{}
+	if len(articleGorms) > limit {
+		articleGorms = articleGorms[:limit]
+		last := articleGorms[limit-1]
+		listing.NextCursor = listcursor.Cursor{
+			IndexedTimestamp: last.IndexedTimestamp,
+			ID:               last.ID,
+		}.Encode()
+	}
+
+	listing.Articles, err = convertArticleGorms(articleGorms)
+	if err != nil {
+		return nil, err
+	}
+	return listing, nil
+}
+
+// IterateArticles returns an Iterator over all the articles matching the filter, in the
+// order of ListArticles
+func (p *ArticlePersister) IterateArticles(filter *article.ArticleFilter, batchSize int) article.Iterator {
+	return article.NewListIterator(func(page *article.PageRequest) (*article.ArticleListing, error) {
+		return p.ListArticles(filter, page)
+	}, batchSize)
+}
+
+// DeleteArticle soft deletes the article with the given ID.
+// Returns article.ErrArticleNotFound if there is no article that is not already deleted.
+func (p *ArticlePersister) DeleteArticle(articleID uint) error {
+	result := p.DB.Where("id = ?", articleID).Delete(&ArticleGorm{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return article.ErrArticleNotFound
+	}
+	return nil
+}
+
+// RestoreArticle restores a soft deleted article with the given ID.
+// Returns article.ErrArticleNotFound if there is no deleted article.
+func (p *ArticlePersister) RestoreArticle(articleID uint) error {
+	result := p.DB.Unscoped().Model(&ArticleGorm{}).
+		Where("id = ? AND deleted_at IS NOT NULL", articleID).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return article.ErrArticleNotFound
+	}
+	return nil
+}
+
+// PurgeArticle permanently deletes the article with the given ID, whether or not it was
+// soft deleted. Returns article.ErrArticleNotFound if there is no article.
+func (p *ArticlePersister) PurgeArticle(articleID uint) error {
+	result := p.DB.Unscoped().Where("id = ?", articleID).Delete(&ArticleGorm{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return article.ErrArticleNotFound
+	}
+	return nil
+}
+
+// lookupDB returns the db to use for a lookup with the given options
+func lookupDB(db *gorm.DB, opts []article.LookupOption) *gorm.DB {
+	if article.NewLookupOptions(opts...).IncludeDeleted {
+		return db.Unscoped()
+	}
+	return db
+}
+
+// applyArticleFilter adds the conditions in the filter to the query. It follows the
+// filter of article.GormPGPersister using the sqlite json functions.
+func applyArticleFilter(db *gorm.DB, filter *article.ArticleFilter) (*gorm.DB, error) {
+	if filter == nil {
+		return db, nil
+	}
+	if filter.RawJSON != nil {
+		return nil, article.ErrRawJSONQueryNotSupported
+	}
+
+	if filter.IncludeDeleted {
+		db = db.Unscoped()
+	}
+	if filter.NewsroomAddress != "" {
+		db = db.Where("articles.newsroom_address = ?", article.NormalizeNewsroomAddress(filter.NewsroomAddress))
+	}
+	if !filter.IndexedAfter.IsZero() {
+		db = db.Where("articles.indexed_timestamp >= ?", filter.IndexedAfter.UTC())
+	}
+	if !filter.IndexedBefore.IsZero() {
+		db = db.Where("articles.indexed_timestamp < ?", filter.IndexedBefore.UTC())
+	}
+	// julianday parses the RFC 3339 dates in the metadata, including their offset
+	if !filter.PublishedAfter.IsZero() {
+		db = db.Where(
+			"julianday(json_extract(articles.article_metadata, '$.OriginalPublishDate')) >= julianday(?)",
+			filter.PublishedAfter.UTC().Format(time.RFC3339Nano),
+		)
+	}
+	if !filter.PublishedBefore.IsZero() {
+		db = db.Where(
+			"julianday(json_extract(articles.article_metadata, '$.OriginalPublishDate')) < julianday(?)",
+			filter.PublishedBefore.UTC().Format(time.RFC3339Nano),
+		)
+	}
+	if filter.Tag != "" {
+		db = db.Where(
+			"(EXISTS (SELECT 1 FROM json_each(articles.article_metadata, '$.Tags') WHERE json_each.value = ?) "+
+				"OR json_extract(articles.article_metadata, '$.PrimaryTag') = ?)",
+			filter.Tag,
+			filter.Tag,
+		)
+	}
+	if filter.HasBlockData != nil {
+		if *filter.HasBlockData {
+			db = db.Where("articles.block_data IS NOT NULL")
+		} else {
+			db = db.Where("articles.block_data IS NULL")
+		}
+	}
+
+	return db, nil
+}
+
+// confirmAnchorState moves the article to confirmed if it is not already
+func confirmAnchorState(tx *gorm.DB, articleID uint) error {
+	articleGorm := &ArticleGorm{}
+	if err := tx.Select("id, anchor_state").First(articleGorm, articleID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return article.ErrArticleNotFound
+		}
+		return errors.Wrap(err, "error finding article")
+	}
+	if articleGorm.AnchorState == article.AnchorStateConfirmed {
+		return nil
+	}
+
+	transition := &article.Gorm{AnchorState: articleGorm.AnchorState}
+	if err := transition.TransitionTo(article.AnchorStateConfirmed); err != nil {
+		return err
+	}
+	return tx.Model(articleGorm).Update("anchor_state", transition.AnchorState).Error
+}
+
+func convertArticleGorms(articleGorms []ArticleGorm) ([]carticle.Article, error) {
+	articles := make([]carticle.Article, len(articleGorms))
+	for i := range articleGorms {
+		converted, err := articleGorms[i].ConvertToArticle()
+		if err != nil {
+			return nil, err
+		}
+		articles[i] = *converted
+	}
+	return articles, nil
+}
@@ -0,0 +1,194 @@
+package sqlite_test
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/joincivil/go-common-priv/pkg/models/article"
+	"github.com/joincivil/go-common-priv/pkg/models/sqlite"
+	"github.com/joincivil/go-common-priv/pkg/models/testutils"
+	carticle "github.com/joincivil/go-common/pkg/article"
+)
+
+func testArticleFunc(persister article.Persister) {}
+
+func TestArticlePersister(t *testing.T) {
+	persister, err := sqlite.NewArticlePersister(":memory:")
+	if err != nil {
+		t.Fatalf("should have opened sqlite: err: %v", err)
+	}
+	defer persister.DB.Close() // nolint: errcheck
+	testArticleFunc(persister)
+
+	newsroomAddr := "0x7c722B8AC728aDd7780a66017e8daDBa530EE261"
+	now := time.Now()
+
+	first := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Title:               "first",
+			CanonicalURL:        "https://newstuff.bz/sqlitearticle",
+			RevisionContentHash: "0xfirst",
+			Tags:                []string{"news"},
+			OriginalPublishDate: now.Add(-time.Hour),
+		},
+		NewsroomAddress:  newsroomAddr,
+		IndexedTimestamp: now,
+		RawJSON:          []byte(`{"title": "first"}`),
+	}
+	second := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Title:               "second",
+			CanonicalURL:        "https://newstuff.bz/sqlitearticle",
+			PrimaryTag:          "news",
+			OriginalPublishDate: now,
+		},
+		NewsroomAddress:  newsroomAddr,
+		IndexedTimestamp: now,
+	}
+	third := &carticle.Article{
+		ArticleMetadata:  carticle.Metadata{Title: "third"},
+		NewsroomAddress:  "0x3e39fa983abcd349d95aed608e798817397cf0d1",
+		IndexedTimestamp: now.Add(time.Second),
+	}
+	for _, a := range []*carticle.Article{first, second, third} {
+		if err := persister.CreateArticle(a); err != nil {
+			t.Fatalf("should have created article: err: %v", err)
+		}
+	}
+	if first.ID == 0 || second.ID <= first.ID {
+		t.Errorf("should have assigned increasing ids")
+	}
+
+	found, err := persister.ArticleByID(first.ID)
+	if err != nil {
+		t.Fatalf("should have found the article: err: %v", err)
+	}
+	if string(found.RawJSON) != `{"title": "first"}` || found.ArticleMetadata.Title != "first" {
+		t.Errorf("should have round tripped the json: %v", string(found.RawJSON))
+	}
+	if !found.IndexedTimestamp.Equal(now) {
+		t.Errorf("should have round tripped the indexed timestamp: %v", found.IndexedTimestamp)
+	}
+	if _, err := persister.ArticleByID(1000); err != gorm.ErrRecordNotFound {
+		t.Errorf("should have returned gorm.ErrRecordNotFound: err: %v", err)
+	}
+
+	found, err = persister.ArticleByCanonicalURL("https://newstuff.bz/sqlitearticle")
+	if err != nil || found.ID != second.ID {
+		t.Errorf("should have found the most recent article by canonical url: err: %v", err)
+	}
+	found, err = persister.ArticleByRevisionContentHash("0xfirst")
+	if err != nil || found.ID != first.ID {
+		t.Errorf("should have found the article by content hash: err: %v", err)
+	}
+	if _, err := persister.ArticleByRevisionContentHash("0xnone"); err != article.ErrArticleNotFound {
+		t.Errorf("should have returned ErrArticleNotFound: err: %v", err)
+	}
+
+	listing, err := persister.ListArticles(&article.ArticleFilter{Tag: "news"}, &article.PageRequest{Limit: 1})
+	if err != nil {
+		t.Fatalf("should have listed the articles: err: %v", err)
+	}
+	if len(listing.Articles) != 1 || listing.Articles[0].ID != second.ID || listing.NextCursor == "" {
+		t.Fatalf("should have listed the most recent tagged article first: %v", listing.Articles)
+	}
+	listing, err = persister.ListArticles(
+		&article.ArticleFilter{Tag: "news"},
+		&article.PageRequest{Limit: 1, Cursor: listing.NextCursor},
+	)
+	if err != nil {
+		t.Fatalf("should have listed the next page: err: %v", err)
+	}
+	if len(listing.Articles) != 1 || listing.Articles[0].ID != first.ID || listing.NextCursor != "" {
+		t.Errorf("should have listed the first article on the last page: %v", listing.Articles)
+	}
+
+	listing, err = persister.ListArticles(&article.ArticleFilter{
+		IndexedAfter:   now.Add(time.Second).In(time.FixedZone("UTC+5", 5*60*60)),
+		PublishedAfter: time.Time{},
+	}, nil)
+	if err != nil || len(listing.Articles) != 1 || listing.Articles[0].ID != third.ID {
+		t.Errorf("should have compared indexed timestamps across time zones: err: %v", err)
+	}
+	listing, err = persister.ListArticles(&article.ArticleFilter{
+		PublishedAfter:  now.Add(-time.Minute),
+		NewsroomAddress: newsroomAddr,
+	}, nil)
+	if err != nil || len(listing.Articles) != 1 || listing.Articles[0].ID != second.ID {
+		t.Errorf("should have filtered by publish date: err: %v", err)
+	}
+	_, err = persister.ListArticles(&article.ArticleFilter{RawJSON: article.NewRawJSONQuery()}, nil)
+	if err != article.ErrRawJSONQueryNotSupported {
+		t.Errorf("should have returned ErrRawJSONQueryNotSupported: err: %v", err)
+	}
+
+	count := 0
+	it := persister.IterateArticles(nil, 2)
+	for it.Next() {
+		count++
+	}
+	if it.Err() != nil || count != 3 {
+		t.Errorf("should have iterated over all articles: %v err: %v", count, it.Err())
+	}
+
+	first.BlockData = testutils.MakeFakeReceipt()
+	first.BlockData.BlockNumber = big.NewInt(10)
+	if err := persister.UpdateArticle(first); err != nil {
+		t.Fatalf("should have updated the article: err: %v", err)
+	}
+	hasBlockData := true
+	listing, err = persister.ListArticles(&article.ArticleFilter{HasBlockData: &hasBlockData}, nil)
+	if err != nil || len(listing.Articles) != 1 || listing.Articles[0].ID != first.ID {
+		t.Errorf("should have listed the article with block data: err: %v", err)
+	}
+	if listing.Articles[0].BlockData.BlockNumber.Uint64() != 10 {
+		t.Errorf("should have round tripped the block data: %v", listing.Articles[0].BlockData)
+	}
+	articleGorm := &sqlite.ArticleGorm{}
+	if err := persister.DB.First(articleGorm, first.ID).Error; err != nil {
+		t.Fatalf("should have found the article gorm: err: %v", err)
+	}
+	if articleGorm.AnchorState != article.AnchorStateConfirmed {
+		t.Errorf("should have confirmed the article with block data: %v", articleGorm.AnchorState)
+	}
+
+	first.RawJSON = []byte(`{not json`)
+	if err := persister.UpdateArticle(first); err == nil {
+		t.Errorf("should not have saved invalid raw json")
+	}
+
+	if err := persister.DeleteArticle(third.ID); err != nil {
+		t.Errorf("should have deleted the article: err: %v", err)
+	}
+	if _, err := persister.ArticleByID(third.ID); err != gorm.ErrRecordNotFound {
+		t.Errorf("should not have found the deleted article: err: %v", err)
+	}
+	if _, err := persister.ArticleByID(third.ID, article.IncludeDeleted()); err != nil {
+		t.Errorf("should have found the deleted article: err: %v", err)
+	}
+	if err := persister.RestoreArticle(third.ID); err != nil {
+		t.Errorf("should have restored the article: err: %v", err)
+	}
+	if err := persister.RestoreArticle(third.ID); err != article.ErrArticleNotFound {
+		t.Errorf("should have returned ErrArticleNotFound: err: %v", err)
+	}
+	if err := persister.PurgeArticle(third.ID); err != nil {
+		t.Errorf("should have purged the article: err: %v", err)
+	}
+	if err := persister.PurgeArticle(third.ID); err != article.ErrArticleNotFound {
+		t.Errorf("should have returned ErrArticleNotFound: err: %v", err)
+	}
+}
+
+func TestArticlePersisterSuite(t *testing.T) {
+	testutils.RunArticlePersisterSuite(t, func(t *testing.T) (article.Persister, func()) {
+		persister, err := sqlite.NewArticlePersister(":memory:")
+		if err != nil {
+			t.Fatalf("should have opened sqlite: err: %v", err)
+		}
+		return persister, func() { persister.DB.Close() } // nolint: errcheck
+	})
+}
@@ -0,0 +1,245 @@
+package sqlite
+
+import (
+	"encoding/json"
+	"time"
+
+	log "github.com/golang/glog"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+
+	"github.com/joincivil/go-common-priv/pkg/models/article"
+	"github.com/joincivil/go-common-priv/pkg/models/newsroom"
+	carticle "github.com/joincivil/go-common/pkg/article"
+	ceth "github.com/joincivil/go-common/pkg/eth"
+)
+
+// NewsroomGorm is the newsroom schema in sqlite. It has the columns of newsroom.Gorm with
+// the meta stored as text.
+type NewsroomGorm struct {
+	gorm.Model
+	Name    string
+	Address string `gorm:"unique;not null"`
+	Meta    JSONText
+}
+
+// TableName sets the name of the corresponding table in the db
+func (NewsroomGorm) TableName() string {
+	return "newsrooms"
+}
+
+// ConvertToNewsroom returns the gorm struct as the public newsroom struct
+func (n *NewsroomGorm) ConvertToNewsroom() (*newsroom.Newsroom, error) {
+	nr := &newsroom.Newsroom{}
+	nr.ID = n.ID
+	nr.Name = n.Name
+	nr.Address = n.Address
+
+	var meta *newsroom.Meta
+	if err := json.Unmarshal(n.Meta, &meta); err != nil {
+		return nil, errors.Wrap(err, "error unmarshalling meta")
+	}
+	nr.Meta = meta
+
+	return nr, nil
+}
+
+// NewsroomPersister implements newsroom.Persister on sqlite. The articles are stored in
+// the same database, so the newsroom articles can be used with an ArticlePersister.
+type NewsroomPersister struct {
+	DB *gorm.DB
+}
+
+// NewNewsroomPersister opens the sqlite database at path and returns a newsroom persister
+// that uses it. See Open.
+func NewNewsroomPersister(path string) (*NewsroomPersister, error) {
+	db, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &NewsroomPersister{DB: db}, nil
+}
+
+// NewNewsroomPersisterWithDB uses an existing gorm.DB struct to create a new NewsroomPersister.
+// This is useful to share the database with an ArticlePersister.
+func NewNewsroomPersisterWithDB(db *gorm.DB) (*NewsroomPersister, error) {
+	return &NewsroomPersister{DB: db}, nil
+}
+
+// CreateNewsroom takes a newsroom struct and saves it to the db
+func (p *NewsroomPersister) CreateNewsroom(nr *newsroom.Newsroom) error {
+	bys, err := json.Marshal(nr.Meta)
+	if err != nil {
+		return errors.Wrap(err, "error marshalling metadata")
+	}
+
+	newsroomGorm := NewsroomGorm{
+		Name:    nr.Name,
+		Address: ceth.NormalizeEthAddress(nr.Address),
+		Meta:    JSONText(bys),
+	}
+
+	if err := p.DB.Create(&newsroomGorm).Error; err != nil {
+		return err
+	}
+
+	nr.ID = newsroomGorm.ID
+
+	return nil
+}
+
+// UpdateNewsroom takes a newsroom struct that has an id and updates it with new values
+func (p *NewsroomPersister) UpdateNewsroom(nr *newsroom.Newsroom) error {
+	newsroomGorm := NewsroomGorm{}
+
+	if err := p.DB.First(&newsroomGorm, nr.ID).Error; err != nil {
+		return err
+	}
+
+	newsroomGorm.Name = nr.Name
+	newsroomGorm.Address = ceth.NormalizeEthAddress(nr.Address)
+
+	bys, err := json.Marshal(nr.Meta)
+	if err != nil {
+		return errors.Wrap(err, "error marshalling metadata")
+	}
+	newsroomGorm.Meta = JSONText(bys)
+
+	return p.DB.Save(&newsroomGorm).Error
+}
+
+// AddArticle adds an article to a newsroom with the given ID. As with
+// newsroom.GormPGPersister, the ID is not set on the given article, and an article
+// with block data is added as confirmed.
+func (p *NewsroomPersister) AddArticle(newsroomID uint, newArticle *carticle.Article) error {
+	articleGorm := ArticleGorm{}
+	if err := articleGorm.PopulateFromArticle(newArticle); err != nil {
+		return err
+	}
+	articleGorm.AnchorState = article.AnchorStatePending
+	if articleGorm.TxHash != "" {
+		articleGorm.AnchorState = article.AnchorStateConfirmed
+	}
+
+	newsroomGorm := NewsroomGorm{}
+	if err := p.DB.First(&newsroomGorm, newsroomID).Error; err != nil {
+		return err
+	}
+
+	articleGorm.NewsroomAddress = newsroomGorm.Address
+	return p.DB.Create(&articleGorm).Error
+}
+
+// Newsrooms returns the list of newsrooms
+func (p *NewsroomPersister) Newsrooms() ([]*newsroom.Newsroom, error) {
+	newsroomGorms := []NewsroomGorm{}
+
+	if err := p.DB.Order("id ASC").Find(&newsroomGorms).Error; err != nil {
+		return nil, err
+	}
+
+	newsrooms := make([]*newsroom.Newsroom, len(newsroomGorms))
+	for ind := range newsroomGorms {
+		nr, err := newsroomGorms[ind].ConvertToNewsroom()
+		if err != nil {
+			log.Errorf("error unmarshalling meta: err: %v", err)
+			continue
+		}
+		newsrooms[ind] = nr
+	}
+
+	return newsrooms, nil
+}
+
+// NewsroomByID returns the newsroom with the given ID if its found
+func (p *NewsroomPersister) NewsroomByID(newsroomID uint) (*newsroom.Newsroom, error) {
+	newsroomGorm := NewsroomGorm{}
+
+	if err := p.DB.First(&newsroomGorm, newsroomID).Error; err != nil {
+		return nil, err
+	}
+
+	return newsroomGorm.ConvertToNewsroom()
+}
+
+// NewsroomByAddress returns the newsroom with the given eth address if its found
+func (p *NewsroomPersister) NewsroomByAddress(addr string) (*newsroom.Newsroom, error) {
+	newsroomGorm := NewsroomGorm{}
+
+	normalizedAddr := ceth.NormalizeEthAddress(addr)
+	if err := p.DB.Where("address = ?", normalizedAddr).First(&newsroomGorm).Error; err != nil {
+		return nil, err
+	}
+
+	return newsroomGorm.ConvertToNewsroom()
+}
+
+// GetArticlesForNewsroom returns all the articles for a newsroom with the given ID
+func (p *NewsroomPersister) GetArticlesForNewsroom(newsroomID uint) ([]carticle.Article, error) {
+	return p.articlesForNewsroom(newsroomID, func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	})
+}
+
+// GetArticlesForNewsroomIndexedSinceDate returns all articles for a newsroom indexed after the date
+func (p *NewsroomPersister) GetArticlesForNewsroomIndexedSinceDate(newsroomID uint,
+	date time.Time) ([]carticle.Article, error) {
+	return p.articlesForNewsroom(newsroomID, func(db *gorm.DB) *gorm.DB {
+		return db.Where("indexed_timestamp >= ?", date.UTC()).Order("id ASC")
+	})
+}
+
+// GetLatestArticleForNewsroom returns the latest article for a newsroom with the given ID.
+// Like newsroom.GormPGPersister, articles are ordered by the text of their
+// OriginalPublishDate as stored in the metadata json.
+func (p *NewsroomPersister) GetLatestArticleForNewsroom(newsroomID uint) (*carticle.Article, error) {
+	articles, err := p.articlesForNewsroom(newsroomID, func(db *gorm.DB) *gorm.DB {
+		return db.Order(metadataField("OriginalPublishDate") + " DESC").Limit(1)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(articles) == 0 {
+		return nil, newsroom.ErrNoArticles
+	}
+
+	return &articles[0], nil
+}
+
+// IterateArticlesForNewsroom returns an iterator over the articles for a newsroom with the
+// given ID, most recently indexed first. The articles are fetched in batches of batchSize,
+// or the default page size if batchSize is 0.
+func (p *NewsroomPersister) IterateArticlesForNewsroom(newsroomID uint, batchSize int) (article.Iterator, error) {
+	newsroomGorm := NewsroomGorm{}
+	if err := p.DB.First(&newsroomGorm, newsroomID).Error; err != nil {
+		return nil, err
+	}
+
+	articlePersister := &ArticlePersister{DB: p.DB}
+	return articlePersister.IterateArticles(
+		&article.ArticleFilter{NewsroomAddress: newsroomGorm.Address},
+		batchSize,
+	), nil
+}
+
+// articlesForNewsroom returns the articles for a newsroom with the given ID matching the
+// query built by scope
+func (p *NewsroomPersister) articlesForNewsroom(newsroomID uint,
+	scope func(db *gorm.DB) *gorm.DB) ([]carticle.Article, error) {
+	newsroomGorm := NewsroomGorm{}
+	if err := p.DB.First(&newsroomGorm, newsroomID).Error; err != nil {
+		return nil, err
+	}
+
+	articleGorms := []ArticleGorm{}
+	err := p.DB.Scopes(scope).
+		Where("newsroom_address = ?", newsroomGorm.Address).
+		Find(&articleGorms).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return convertArticleGorms(articleGorms)
+}
@@ -0,0 +1,16 @@
+//go:build !sqlite_json
+// +build !sqlite_json
+
+package sqlite_test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+// TestMain skips the tests when go-sqlite3 is built without the json functions
+func TestMain(m *testing.M) {
+	fmt.Println("skipping the sqlite tests, run them with -tags sqlite_json")
+	os.Exit(0)
+}
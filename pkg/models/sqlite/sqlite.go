@@ -0,0 +1,128 @@
+// Package sqlite implements the article and newsroom persisters on sqlite, for tools
+// that run without a database server. The json columns are stored as text and queried
+// with the sqlite json functions in place of the postgres jsonb operators, which
+// go-sqlite3 only includes when built with the sqlite_json tag:
+//
+//	go build -tags sqlite_json
+package sqlite
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite" // need the sqlite driver
+	"github.com/pkg/errors"
+
+	gormutils "github.com/joincivil/go-common-priv/pkg/utils/gorm"
+)
+
+var (
+	// ErrInvalidJSON indicates that a json column was set to invalid json, which postgres
+	// rejects for jsonb columns
+	ErrInvalidJSON = errors.New("invalid json")
+	// ErrJSONNotSupported indicates that go-sqlite3 was built without the json functions,
+	// add the sqlite_json build tag
+	ErrJSONNotSupported = errors.New("sqlite json functions not supported, build with -tags sqlite_json")
+
+	// The persisters do not retry transactions, they set the IDs of the structs they save
+	txOptions = &gormutils.TxOptions{MaxAttempts: 1}
+)
+
+// JSONText is a json column stored as text, so it can be queried with the sqlite json
+// functions. Empty json is stored as NULL, as with postgres.Jsonb.
+type JSONText []byte
+
+// GormDataType sets the column type of the field
+func (JSONText) GormDataType(gorm.Dialect) string {
+	return "text"
+}
+
+// Value implements the driver.Valuer interface
+func (j JSONText) Value() (driver.Value, error) {
+	if len(j) == 0 {
+		return nil, nil
+	}
+	if !json.Valid(j) {
+		return nil, ErrInvalidJSON
+	}
+	return string(j), nil
+}
+
+// Scan implements the sql.Scanner interface
+func (j *JSONText) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*j = nil
+	case string:
+		*j = JSONText(v)
+	case []byte:
+		*j = append(JSONText(nil), v...)
+	default:
+		return errors.Errorf("cannot scan %T into JSONText", value)
+	}
+	return nil
+}
+
+// Open opens the sqlite database at path, creating it and its schema if they do not
+// exist. Use ":memory:" for a database that only lives as long as the returned DB.
+func Open(path string) (*gorm.DB, error) {
+	db, err := gorm.Open("sqlite3", path)
+	if err != nil {
+		return nil, errors.Wrap(err, "error opening sqlite")
+	}
+
+	// sqlite allows a single writer at a time, and every connection to ":memory:"
+	// is a separate database
+	db.DB().SetMaxOpenConns(1)
+
+	if err := Migrate(db); err != nil {
+		db.Close() // nolint: errcheck
+		return nil, err
+	}
+	return db, nil
+}
+
+// Migrate makes sure the sqlite schema is up to date. It adds the expression indices on
+// the article metadata used for lookups and the index used for listing articles.
+func Migrate(db *gorm.DB) error {
+	if err := db.Exec("SELECT json('{}')").Error; err != nil {
+		return errors.Wrap(ErrJSONNotSupported, err.Error())
+	}
+	if err := db.AutoMigrate(&NewsroomGorm{}, &ArticleGorm{}).Error; err != nil {
+		return errors.Wrap(err, "error migrating sqlite schema")
+	}
+
+	tblName := ArticleGorm{}.TableName()
+	indexQueries := []string{
+		fmt.Sprintf(
+			"CREATE INDEX IF NOT EXISTS idx_%s_indexed_timestamp_id ON %s (indexed_timestamp, id)",
+			tblName,
+			tblName,
+		),
+	}
+	for _, field := range []string{"CanonicalURL", "RevisionContentHash"} {
+		indexQueries = append(indexQueries, fmt.Sprintf(
+			"CREATE INDEX IF NOT EXISTS idx_%s_metadata_%s ON %s (%s)",
+			tblName,
+			strings.ToLower(field),
+			tblName,
+			metadataField(field),
+		))
+	}
+
+	for _, indexQuery := range indexQueries {
+		if err := db.Exec(indexQuery).Error; err != nil {
+			return errors.Wrap(err, "error adding sqlite index")
+		}
+	}
+	return nil
+}
+
+// metadataField returns the expression for a field of the article metadata. Like ->> in
+// postgres, json_extract returns strings as text.
+func metadataField(field string) string {
+	return fmt.Sprintf("json_extract(article_metadata, '$.%s')", field)
+}
@@ -0,0 +1,497 @@
+package testutils
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/jinzhu/gorm"
+
+	"github.com/joincivil/go-common-priv/pkg/models/article"
+	carticle "github.com/joincivil/go-common/pkg/article"
+)
+
+// ArticlePersisterFactory returns the article persister to run a test of the suite
+// against, and a func that cleans up after the test. The persister may already hold
+// articles, the tests only look at the articles they create.
+type ArticlePersisterFactory func(t *testing.T) (article.Persister, func())
+
+// RunArticlePersisterSuite runs the tests every article.Persister has to pass, each
+// against a persister from factory
+func RunArticlePersisterSuite(t *testing.T, factory ArticlePersisterFactory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, persister article.Persister)
+	}{
+		{"CreateArticle", testCreateArticle},
+		{"ArticleByIDNotFound", testArticleByIDNotFound},
+		{"ArticleByMetadata", testArticleByMetadata},
+		{"UpdateArticle", testUpdateArticle},
+		{"ListArticlesOrder", testListArticlesOrder},
+		{"ListArticlesIndexedBoundaries", testListArticlesIndexedBoundaries},
+		{"ListArticlesFilters", testListArticlesFilters},
+		{"ListArticlesInvalidCursor", testListArticlesInvalidCursor},
+		{"IterateArticles", testIterateArticles},
+		{"DeleteRestorePurge", testDeleteRestorePurge},
+	}
+
+	for _, tt := range tests {
+		test := tt.test
+		t.Run(tt.name, func(t *testing.T) {
+			persister, cleanup := factory(t)
+			defer cleanup()
+			test(t, persister)
+		})
+	}
+}
+
+func testCreateArticle(t *testing.T, persister article.Persister) {
+	newsroomAddr := randomAddress()
+	indexed := suiteTime()
+	a := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Title:        "created",
+			CanonicalURL: randomURL(),
+			Tags:         []string{"news", "politics"},
+		},
+		NewsroomAddress:  strings.ToLower(newsroomAddr),
+		IndexedTimestamp: indexed,
+		RawJSON:          []byte(`{"title": "created", "tags": ["news", "politics"]}`),
+	}
+	if err := persister.CreateArticle(a); err != nil {
+		t.Fatalf("should have created the article: err: %v", err)
+	}
+	if a.ID == 0 {
+		t.Fatalf("should have set the id of the article")
+	}
+
+	found, err := persister.ArticleByID(a.ID)
+	if err != nil {
+		t.Fatalf("should have found the article: err: %v", err)
+	}
+	if found.ID != a.ID {
+		t.Errorf("should have returned the article: %v", found)
+	}
+	if found.NewsroomAddress != newsroomAddr {
+		t.Errorf("should have saved the checksum cased newsroom address: %v", found.NewsroomAddress)
+	}
+	if !reflect.DeepEqual(found.ArticleMetadata, a.ArticleMetadata) {
+		t.Errorf("should have round tripped the metadata: %v", found.ArticleMetadata)
+	}
+	if !found.IndexedTimestamp.Equal(indexed) {
+		t.Errorf("should have round tripped the indexed timestamp: %v", found.IndexedTimestamp)
+	}
+	if !jsonEqual(found.RawJSON, a.RawJSON) {
+		t.Errorf("should have round tripped the raw json: %v", string(found.RawJSON))
+	}
+}
+
+func testArticleByIDNotFound(t *testing.T, persister article.Persister) {
+	if _, err := persister.ArticleByID(math.MaxInt32); !gorm.IsRecordNotFoundError(err) {
+		t.Errorf("should have returned gorm.ErrRecordNotFound: err: %v", err)
+	}
+}
+
+func testArticleByMetadata(t *testing.T, persister article.Persister) {
+	canonicalURL := randomURL()
+	contentHash := "0x" + randomHex(32)
+	older := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			CanonicalURL:        canonicalURL,
+			RevisionContentHash: contentHash,
+		},
+		IndexedTimestamp: suiteTime(),
+	}
+	newer := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			CanonicalURL:        canonicalURL,
+			RevisionContentHash: contentHash,
+		},
+		IndexedTimestamp: suiteTime(),
+	}
+	createArticles(t, persister, older, newer)
+
+	found, err := persister.ArticleByCanonicalURL(canonicalURL)
+	if err != nil || found.ID != newer.ID {
+		t.Errorf("should have found the most recent article by canonical url: %v: err: %v", found, err)
+	}
+	found, err = persister.ArticleByRevisionContentHash(contentHash)
+	if err != nil || found.ID != newer.ID {
+		t.Errorf("should have found the most recent article by content hash: %v: err: %v", found, err)
+	}
+
+	if _, err := persister.ArticleByCanonicalURL(randomURL()); err != article.ErrArticleNotFound {
+		t.Errorf("should have returned ErrArticleNotFound: err: %v", err)
+	}
+	if _, err := persister.ArticleByRevisionContentHash("0x" + randomHex(32)); err != article.ErrArticleNotFound {
+		t.Errorf("should have returned ErrArticleNotFound: err: %v", err)
+	}
+
+	if err := persister.DeleteArticle(newer.ID); err != nil {
+		t.Fatalf("should have deleted the article: err: %v", err)
+	}
+	found, err = persister.ArticleByCanonicalURL(canonicalURL)
+	if err != nil || found.ID != older.ID {
+		t.Errorf("should not have found the deleted article: %v: err: %v", found, err)
+	}
+	found, err = persister.ArticleByRevisionContentHash(contentHash, article.IncludeDeleted())
+	if err != nil || found.ID != newer.ID {
+		t.Errorf("should have found the deleted article: %v: err: %v", found, err)
+	}
+}
+
+func testUpdateArticle(t *testing.T, persister article.Persister) {
+	a := &carticle.Article{
+		ArticleMetadata:  carticle.Metadata{Title: "before", CanonicalURL: randomURL()},
+		NewsroomAddress:  randomAddress(),
+		IndexedTimestamp: suiteTime(),
+		RawJSON:          []byte(`{"title": "before"}`),
+	}
+	createArticles(t, persister, a)
+
+	a.ArticleMetadata.Title = "after"
+	a.RawJSON = []byte(`{"title": "after"}`)
+	a.BlockData = MakeFakeReceipt()
+	a.BlockData.BlockNumber = big.NewInt(100)
+	if err := persister.UpdateArticle(a); err != nil {
+		t.Fatalf("should have updated the article: err: %v", err)
+	}
+
+	found, err := persister.ArticleByID(a.ID)
+	if err != nil {
+		t.Fatalf("should have found the article: err: %v", err)
+	}
+	if found.ArticleMetadata.Title != "after" || !jsonEqual(found.RawJSON, a.RawJSON) {
+		t.Errorf("should have updated the article: %v", found)
+	}
+	if found.BlockData.TxHash != ethCommon.HexToHash(FakeTxHash) {
+		t.Errorf("should have saved the block data: %v", found.BlockData.TxHash.Hex())
+	}
+	if found.BlockData.BlockNumber == nil || found.BlockData.BlockNumber.Uint64() != 100 {
+		t.Errorf("should have saved the block number: %v", found.BlockData.BlockNumber)
+	}
+}
+
+func testListArticlesOrder(t *testing.T, persister article.Persister) {
+	newsroomAddr := randomAddress()
+	base := suiteTime()
+	articles := []*carticle.Article{
+		{NewsroomAddress: newsroomAddr, IndexedTimestamp: base},
+		{NewsroomAddress: newsroomAddr, IndexedTimestamp: base.Add(2 * time.Second)},
+		{NewsroomAddress: newsroomAddr, IndexedTimestamp: base.Add(time.Second)},
+		{NewsroomAddress: newsroomAddr, IndexedTimestamp: base.Add(time.Second)},
+		{NewsroomAddress: newsroomAddr, IndexedTimestamp: base.Add(-time.Second)},
+	}
+	createArticles(t, persister, articles...)
+
+	// Most recently indexed first, by id for the same indexed timestamp
+	expected := []uint{articles[1].ID, articles[3].ID, articles[2].ID, articles[0].ID, articles[4].ID}
+
+	listed := []uint{}
+	page := &article.PageRequest{Limit: 2}
+	for i := 0; i < len(articles); i++ {
+		listing, err := persister.ListArticles(&article.ArticleFilter{NewsroomAddress: newsroomAddr}, page)
+		if err != nil {
+			t.Fatalf("should have listed the articles: err: %v", err)
+		}
+		if len(listing.Articles) > page.Limit {
+			t.Errorf("should not have listed more than the limit: %v", len(listing.Articles))
+		}
+		for _, a := range listing.Articles {
+			listed = append(listed, a.ID)
+		}
+		if listing.NextCursor == "" {
+			break
+		}
+		page.Cursor = listing.NextCursor
+	}
+
+	if !reflect.DeepEqual(listed, expected) {
+		t.Errorf("should have listed the articles in order: %v, expected %v", listed, expected)
+	}
+}
+
+func testListArticlesIndexedBoundaries(t *testing.T, persister article.Persister) {
+	newsroomAddr := randomAddress()
+	base := suiteTime()
+	before := &carticle.Article{NewsroomAddress: newsroomAddr, IndexedTimestamp: base.Add(-time.Second)}
+	at := &carticle.Article{NewsroomAddress: newsroomAddr, IndexedTimestamp: base}
+	after := &carticle.Article{NewsroomAddress: newsroomAddr, IndexedTimestamp: base.Add(time.Second)}
+	createArticles(t, persister, before, at, after)
+
+	// The boundaries are compared as instants, whatever their time zone
+	boundary := base.In(time.FixedZone("UTC-7", -7*60*60))
+
+	listed := listArticleIDs(t, persister, &article.ArticleFilter{
+		NewsroomAddress: newsroomAddr,
+		IndexedAfter:    boundary,
+	})
+	if !reflect.DeepEqual(listed, []uint{after.ID, at.ID}) {
+		t.Errorf("IndexedAfter should include the boundary: %v", listed)
+	}
+
+	listed = listArticleIDs(t, persister, &article.ArticleFilter{
+		NewsroomAddress: newsroomAddr,
+		IndexedBefore:   boundary,
+	})
+	if !reflect.DeepEqual(listed, []uint{before.ID}) {
+		t.Errorf("IndexedBefore should exclude the boundary: %v", listed)
+	}
+
+	listed = listArticleIDs(t, persister, &article.ArticleFilter{
+		NewsroomAddress: newsroomAddr,
+		IndexedAfter:    boundary,
+		IndexedBefore:   boundary.Add(time.Second),
+	})
+	if !reflect.DeepEqual(listed, []uint{at.ID}) {
+		t.Errorf("should have only listed the article at the boundary: %v", listed)
+	}
+}
+
+func testListArticlesFilters(t *testing.T, persister article.Persister) {
+	newsroomAddr := randomAddress()
+	tag := "tag-" + randomHex(4)
+	base := suiteTime()
+	tagged := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Tags:                []string{"other", tag},
+			OriginalPublishDate: base.Add(-time.Hour),
+		},
+		NewsroomAddress:  newsroomAddr,
+		IndexedTimestamp: base,
+	}
+	primary := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			PrimaryTag:          tag,
+			OriginalPublishDate: base,
+		},
+		NewsroomAddress:  newsroomAddr,
+		IndexedTimestamp: base.Add(time.Second),
+	}
+	untagged := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{
+			Tags:                []string{"other"},
+			OriginalPublishDate: base.Add(time.Hour),
+		},
+		NewsroomAddress:  newsroomAddr,
+		IndexedTimestamp: base.Add(2 * time.Second),
+	}
+	createArticles(t, persister, tagged, primary, untagged)
+
+	untagged.BlockData = MakeFakeReceipt()
+	if err := persister.UpdateArticle(untagged); err != nil {
+		t.Fatalf("should have updated the article: err: %v", err)
+	}
+
+	listed := listArticleIDs(t, persister, &article.ArticleFilter{NewsroomAddress: newsroomAddr, Tag: tag})
+	if !reflect.DeepEqual(listed, []uint{primary.ID, tagged.ID}) {
+		t.Errorf("should have listed the articles with the tag or primary tag: %v", listed)
+	}
+	listed = listArticleIDs(t, persister, &article.ArticleFilter{
+		NewsroomAddress: strings.ToLower(newsroomAddr),
+		Tag:             tag,
+	})
+	if !reflect.DeepEqual(listed, []uint{primary.ID, tagged.ID}) {
+		t.Errorf("should have normalized the newsroom address of the filter: %v", listed)
+	}
+
+	listed = listArticleIDs(t, persister, &article.ArticleFilter{
+		NewsroomAddress: newsroomAddr,
+		PublishedAfter:  base,
+	})
+	if !reflect.DeepEqual(listed, []uint{untagged.ID, primary.ID}) {
+		t.Errorf("PublishedAfter should include the boundary: %v", listed)
+	}
+	listed = listArticleIDs(t, persister, &article.ArticleFilter{
+		NewsroomAddress: newsroomAddr,
+		PublishedBefore: base,
+	})
+	if !reflect.DeepEqual(listed, []uint{tagged.ID}) {
+		t.Errorf("PublishedBefore should exclude the boundary: %v", listed)
+	}
+
+	hasBlockData := true
+	listed = listArticleIDs(t, persister, &article.ArticleFilter{
+		NewsroomAddress: newsroomAddr,
+		HasBlockData:    &hasBlockData,
+	})
+	if !reflect.DeepEqual(listed, []uint{untagged.ID}) {
+		t.Errorf("should have listed the articles with block data: %v", listed)
+	}
+	hasBlockData = false
+	listed = listArticleIDs(t, persister, &article.ArticleFilter{
+		NewsroomAddress: newsroomAddr,
+		HasBlockData:    &hasBlockData,
+	})
+	if !reflect.DeepEqual(listed, []uint{primary.ID, tagged.ID}) {
+		t.Errorf("should have listed the articles without block data: %v", listed)
+	}
+}
+
+func testListArticlesInvalidCursor(t *testing.T, persister article.Persister) {
+	_, err := persister.ListArticles(nil, &article.PageRequest{Cursor: "not a cursor"})
+	if err != article.ErrInvalidCursor {
+		t.Errorf("should have returned ErrInvalidCursor: err: %v", err)
+	}
+}
+
+func testIterateArticles(t *testing.T, persister article.Persister) {
+	newsroomAddr := randomAddress()
+	base := suiteTime()
+	articles := make([]*carticle.Article, 5)
+	for i := range articles {
+		articles[i] = &carticle.Article{
+			NewsroomAddress:  newsroomAddr,
+			IndexedTimestamp: base.Add(time.Duration(i) * time.Second),
+		}
+	}
+	createArticles(t, persister, articles...)
+
+	it := persister.IterateArticles(&article.ArticleFilter{NewsroomAddress: newsroomAddr}, 2)
+	defer it.Close() // nolint: errcheck
+
+	iterated := []uint{}
+	for it.Next() {
+		iterated = append(iterated, it.Article().ID)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("should have iterated without error: err: %v", err)
+	}
+
+	expected := []uint{}
+	for i := len(articles) - 1; i >= 0; i-- {
+		expected = append(expected, articles[i].ID)
+	}
+	if !reflect.DeepEqual(iterated, expected) {
+		t.Errorf("should have iterated over the articles in order: %v, expected %v", iterated, expected)
+	}
+}
+
+func testDeleteRestorePurge(t *testing.T, persister article.Persister) {
+	newsroomAddr := randomAddress()
+	a := &carticle.Article{NewsroomAddress: newsroomAddr, IndexedTimestamp: suiteTime()}
+	createArticles(t, persister, a)
+
+	if err := persister.RestoreArticle(a.ID); err != article.ErrArticleNotFound {
+		t.Errorf("should not have restored an article that is not deleted: err: %v", err)
+	}
+	if err := persister.DeleteArticle(a.ID); err != nil {
+		t.Fatalf("should have deleted the article: err: %v", err)
+	}
+	if err := persister.DeleteArticle(a.ID); err != article.ErrArticleNotFound {
+		t.Errorf("should not have deleted the article twice: err: %v", err)
+	}
+	updated := *a
+	updated.ArticleMetadata.Title = "updated while deleted"
+	if err := persister.UpdateArticle(&updated); err != article.ErrArticleNotFound {
+		t.Errorf("should not have updated the deleted article: err: %v", err)
+	}
+
+	if _, err := persister.ArticleByID(a.ID); !gorm.IsRecordNotFoundError(err) {
+		t.Errorf("should not have found the deleted article: err: %v", err)
+	}
+	if _, err := persister.ArticleByID(a.ID, article.IncludeDeleted()); err != nil {
+		t.Errorf("should have found the deleted article: err: %v", err)
+	}
+	if listed := listArticleIDs(t, persister, &article.ArticleFilter{NewsroomAddress: newsroomAddr}); len(listed) != 0 {
+		t.Errorf("should not have listed the deleted article: %v", listed)
+	}
+	listed := listArticleIDs(t, persister, &article.ArticleFilter{
+		NewsroomAddress: newsroomAddr,
+		IncludeDeleted:  true,
+	})
+	if !reflect.DeepEqual(listed, []uint{a.ID}) {
+		t.Errorf("should have listed the deleted article: %v", listed)
+	}
+
+	if err := persister.RestoreArticle(a.ID); err != nil {
+		t.Fatalf("should have restored the article: err: %v", err)
+	}
+	restored, err := persister.ArticleByID(a.ID)
+	if err != nil {
+		t.Fatalf("should have found the restored article: err: %v", err)
+	}
+	if restored.ArticleMetadata.Title != a.ArticleMetadata.Title {
+		t.Errorf("should not have saved the update to the deleted article: %v", restored.ArticleMetadata.Title)
+	}
+
+	if err := persister.PurgeArticle(a.ID); err != nil {
+		t.Fatalf("should have purged the article: err: %v", err)
+	}
+	if err := persister.PurgeArticle(a.ID); err != article.ErrArticleNotFound {
+		t.Errorf("should not have purged the article twice: err: %v", err)
+	}
+	if _, err := persister.ArticleByID(a.ID, article.IncludeDeleted()); !gorm.IsRecordNotFoundError(err) {
+		t.Errorf("should not have found the purged article: err: %v", err)
+	}
+	if err := persister.DeleteArticle(a.ID); err != article.ErrArticleNotFound {
+		t.Errorf("should not have deleted the purged article: err: %v", err)
+	}
+}
+
+func createArticles(t *testing.T, persister article.Persister, articles ...*carticle.Article) {
+	for _, a := range articles {
+		if err := persister.CreateArticle(a); err != nil {
+			t.Fatalf("should have created the article: err: %v", err)
+		}
+	}
+}
+
+// listArticleIDs returns the IDs of the first page of articles matching the filter
+func listArticleIDs(t *testing.T, persister article.Persister, filter *article.ArticleFilter) []uint {
+	listing, err := persister.ListArticles(filter, nil)
+	if err != nil {
+		t.Fatalf("should have listed the articles: err: %v", err)
+	}
+	ids := []uint{}
+	for _, a := range listing.Articles {
+		ids = append(ids, a.ID)
+	}
+	return ids
+}
+
+// suiteTime returns the current time truncated to the second, so it survives the
+// precision of every backend
+func suiteTime() time.Time {
+	return time.Now().Truncate(time.Second)
+}
+
+func randomHex(n int) string {
+	bys := make([]byte, n)
+	if _, err := rand.Read(bys); err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("%x", bys)
+}
+
+// randomAddress returns a random checksummed eth address, so the articles and newsrooms
+// of a test do not collide with others in the same db
+func randomAddress() string {
+	return ethCommon.HexToAddress(randomHex(20)).Hex()
+}
+
+func randomURL() string {
+	return "https://newstuff.bz/" + randomHex(8)
+}
+
+func jsonEqual(a, b []byte) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+	var va, vb interface{}
+	if err := json.Unmarshal(a, &va); err != nil {
+		return false
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
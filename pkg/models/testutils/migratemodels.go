@@ -3,11 +3,10 @@ package testutils
 import (
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/postgres" // need postgres drivers
-	"github.com/joincivil/go-common-priv/pkg/models/article"
-	"github.com/joincivil/go-common-priv/pkg/models/newsroom"
+	"github.com/joincivil/go-common-priv/pkg/models/migrations"
 )
 
 // MigrateModels makes sure the db schema is up to date when the test runs
 func MigrateModels(db *gorm.DB) error {
-	return db.AutoMigrate(&newsroom.Gorm{}, &article.Gorm{}).Error
+	return migrations.Up(db)
 }
@@ -0,0 +1,334 @@
+package testutils
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/joincivil/go-common-priv/pkg/models/newsroom"
+	carticle "github.com/joincivil/go-common/pkg/article"
+)
+
+// NewsroomPersisterFactory returns the newsroom persister to run a test of the suite
+// against, and a func that cleans up after the test. The persister may already hold
+// newsrooms, the tests only look at the newsrooms they create.
+type NewsroomPersisterFactory func(t *testing.T) (newsroom.Persister, func())
+
+// RunNewsroomPersisterSuite runs the tests every newsroom.Persister has to pass, each
+// against a persister from factory
+func RunNewsroomPersisterSuite(t *testing.T, factory NewsroomPersisterFactory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, persister newsroom.Persister)
+	}{
+		{"CreateNewsroom", testCreateNewsroom},
+		{"MetaRoundTrip", testMetaRoundTrip},
+		{"AddressCaseInsensitive", testAddressCaseInsensitive},
+		{"NewsroomNotFound", testNewsroomNotFound},
+		{"NoArticles", testNoArticles},
+		{"AddArticle", testAddArticle},
+		{"IndexedSinceDateBoundary", testIndexedSinceDateBoundary},
+		{"LatestArticle", testLatestArticle},
+		{"IterateArticlesForNewsroom", testIterateArticlesForNewsroom},
+		{"UpdateNewsroom", testUpdateNewsroom},
+	}
+
+	for _, tt := range tests {
+		test := tt.test
+		t.Run(tt.name, func(t *testing.T) {
+			persister, cleanup := factory(t)
+			defer cleanup()
+			test(t, persister)
+		})
+	}
+}
+
+func testCreateNewsroom(t *testing.T, persister newsroom.Persister) {
+	nr := createNewsroom(t, persister, &newsroom.Meta{Index: true})
+
+	found, err := persister.NewsroomByID(nr.ID)
+	if err != nil {
+		t.Fatalf("should have found the newsroom: err: %v", err)
+	}
+	if found.ID != nr.ID || found.Name != nr.Name || found.Address != nr.Address {
+		t.Errorf("should have returned the newsroom: %v", found)
+	}
+}
+
+func testMetaRoundTrip(t *testing.T, persister newsroom.Persister) {
+	metas := []*newsroom.Meta{
+		{Index: true, Claim: false},
+		{Index: false, Claim: true},
+		{Index: true, Claim: true},
+		{},
+		nil,
+	}
+	for _, meta := range metas {
+		nr := createNewsroom(t, persister, meta)
+
+		found, err := persister.NewsroomByID(nr.ID)
+		if err != nil {
+			t.Fatalf("should have found the newsroom: err: %v", err)
+		}
+		if !reflect.DeepEqual(found.Meta, meta) {
+			t.Errorf("should have round tripped the meta: %v, expected %v", found.Meta, meta)
+		}
+	}
+}
+
+func testAddressCaseInsensitive(t *testing.T, persister newsroom.Persister) {
+	checksummed := randomAddress()
+	nr := &newsroom.Newsroom{
+		Name:    "lowercase",
+		Address: strings.ToLower(checksummed),
+		Meta:    &newsroom.Meta{},
+	}
+	if err := persister.CreateNewsroom(nr); err != nil {
+		t.Fatalf("should have created the newsroom: err: %v", err)
+	}
+
+	for _, addr := range []string{checksummed, strings.ToLower(checksummed), "0x" + strings.ToUpper(checksummed[2:])} {
+		found, err := persister.NewsroomByAddress(addr)
+		if err != nil {
+			t.Errorf("should have found the newsroom by %v: err: %v", addr, err)
+			continue
+		}
+		if found.ID != nr.ID || found.Address != checksummed {
+			t.Errorf("should have returned the newsroom with the checksummed address: %v", found)
+		}
+	}
+
+	duplicate := &newsroom.Newsroom{Name: "uppercase", Address: checksummed, Meta: &newsroom.Meta{}}
+	if err := persister.CreateNewsroom(duplicate); err == nil {
+		t.Errorf("should not have created a newsroom with the same address in another case")
+	}
+}
+
+func testNewsroomNotFound(t *testing.T, persister newsroom.Persister) {
+	missingID := uint(math.MaxInt32)
+	if _, err := persister.NewsroomByID(missingID); !gorm.IsRecordNotFoundError(err) {
+		t.Errorf("NewsroomByID should have returned gorm.ErrRecordNotFound: err: %v", err)
+	}
+	if _, err := persister.NewsroomByAddress(randomAddress()); !gorm.IsRecordNotFoundError(err) {
+		t.Errorf("NewsroomByAddress should have returned gorm.ErrRecordNotFound: err: %v", err)
+	}
+	if _, err := persister.GetArticlesForNewsroom(missingID); !gorm.IsRecordNotFoundError(err) {
+		t.Errorf("GetArticlesForNewsroom should have returned gorm.ErrRecordNotFound: err: %v", err)
+	}
+	if _, err := persister.GetLatestArticleForNewsroom(missingID); !gorm.IsRecordNotFoundError(err) {
+		t.Errorf("GetLatestArticleForNewsroom should have returned gorm.ErrRecordNotFound: err: %v", err)
+	}
+	if _, err := persister.IterateArticlesForNewsroom(missingID, 0); !gorm.IsRecordNotFoundError(err) {
+		t.Errorf("IterateArticlesForNewsroom should have returned gorm.ErrRecordNotFound: err: %v", err)
+	}
+	if err := persister.AddArticle(missingID, &carticle.Article{}); !gorm.IsRecordNotFoundError(err) {
+		t.Errorf("AddArticle should have returned gorm.ErrRecordNotFound: err: %v", err)
+	}
+}
+
+func testNoArticles(t *testing.T, persister newsroom.Persister) {
+	nr := createNewsroom(t, persister, &newsroom.Meta{})
+
+	if _, err := persister.GetLatestArticleForNewsroom(nr.ID); err != newsroom.ErrNoArticles {
+		t.Errorf("should have returned ErrNoArticles: err: %v", err)
+	}
+
+	articles, err := persister.GetArticlesForNewsroom(nr.ID)
+	if err != nil || len(articles) != 0 {
+		t.Errorf("should have returned no articles: %v: err: %v", articles, err)
+	}
+	articles, err = persister.GetArticlesForNewsroomIndexedSinceDate(nr.ID, time.Time{})
+	if err != nil || len(articles) != 0 {
+		t.Errorf("should have returned no articles since the date: %v: err: %v", articles, err)
+	}
+
+	it, err := persister.IterateArticlesForNewsroom(nr.ID, 0)
+	if err != nil {
+		t.Fatalf("should have returned an iterator: err: %v", err)
+	}
+	defer it.Close() // nolint: errcheck
+	if it.Next() || it.Err() != nil {
+		t.Errorf("should not have iterated over any articles: err: %v", it.Err())
+	}
+}
+
+func testAddArticle(t *testing.T, persister newsroom.Persister) {
+	nr := createNewsroom(t, persister, &newsroom.Meta{})
+	other := createNewsroom(t, persister, &newsroom.Meta{})
+
+	titles := []string{"first", "second", "third"}
+	addArticles(t, persister, nr.ID, func(i int, a *carticle.Article) {
+		a.ArticleMetadata.Title = titles[i]
+	}, len(titles))
+	addArticles(t, persister, other.ID, nil, 1)
+
+	articles, err := persister.GetArticlesForNewsroom(nr.ID)
+	if err != nil {
+		t.Fatalf("should have returned the articles: err: %v", err)
+	}
+	if len(articles) != len(titles) {
+		t.Fatalf("should have only returned the articles of the newsroom: %v", len(articles))
+	}
+
+	found := map[string]bool{}
+	for _, a := range articles {
+		if a.NewsroomAddress != nr.Address {
+			t.Errorf("should have set the newsroom address of the article: %v", a.NewsroomAddress)
+		}
+		if a.ID == 0 {
+			t.Errorf("should have saved the article with an id")
+		}
+		found[a.ArticleMetadata.Title] = true
+	}
+	for _, title := range titles {
+		if !found[title] {
+			t.Errorf("should have returned the article %v", title)
+		}
+	}
+}
+
+func testIndexedSinceDateBoundary(t *testing.T, persister newsroom.Persister) {
+	nr := createNewsroom(t, persister, &newsroom.Meta{})
+	date := suiteTime()
+
+	titles := []string{"before", "at", "after"}
+	addArticles(t, persister, nr.ID, func(i int, a *carticle.Article) {
+		a.ArticleMetadata.Title = titles[i]
+		a.IndexedTimestamp = date.Add(time.Duration(i-1) * time.Second)
+	}, len(titles))
+
+	articles, err := persister.GetArticlesForNewsroomIndexedSinceDate(nr.ID, date.UTC())
+	if err != nil {
+		t.Fatalf("should have returned the articles: err: %v", err)
+	}
+	found := map[string]bool{}
+	for _, a := range articles {
+		found[a.ArticleMetadata.Title] = true
+	}
+	if len(articles) != 2 || !found["at"] || !found["after"] {
+		t.Errorf("should have returned the articles indexed at or after the date: %v", found)
+	}
+}
+
+func testLatestArticle(t *testing.T, persister newsroom.Persister) {
+	nr := createNewsroom(t, persister, &newsroom.Meta{})
+	now := suiteTime()
+
+	titles := []string{"old", "latest", "mid"}
+	publishDates := []time.Time{now.Add(-2 * time.Hour), now, now.Add(-time.Hour)}
+	addArticles(t, persister, nr.ID, func(i int, a *carticle.Article) {
+		a.ArticleMetadata.Title = titles[i]
+		a.ArticleMetadata.OriginalPublishDate = publishDates[i]
+	}, len(titles))
+
+	latest, err := persister.GetLatestArticleForNewsroom(nr.ID)
+	if err != nil {
+		t.Fatalf("should have found the latest article: err: %v", err)
+	}
+	if latest.ArticleMetadata.Title != "latest" {
+		t.Errorf("should have returned the latest published article: %v", latest.ArticleMetadata.Title)
+	}
+	if !latest.ArticleMetadata.OriginalPublishDate.Equal(now) {
+		t.Errorf("should have round tripped the publish date: %v", latest.ArticleMetadata.OriginalPublishDate)
+	}
+}
+
+func testIterateArticlesForNewsroom(t *testing.T, persister newsroom.Persister) {
+	nr := createNewsroom(t, persister, &newsroom.Meta{})
+	base := suiteTime()
+	addArticles(t, persister, nr.ID, func(i int, a *carticle.Article) {
+		a.IndexedTimestamp = base.Add(time.Duration(i) * time.Second)
+	}, 5)
+
+	it, err := persister.IterateArticlesForNewsroom(nr.ID, 2)
+	if err != nil {
+		t.Fatalf("should have returned an iterator: err: %v", err)
+	}
+	defer it.Close() // nolint: errcheck
+
+	var last *carticle.Article
+	count := 0
+	for it.Next() {
+		a := it.Article()
+		if a.NewsroomAddress != nr.Address {
+			t.Errorf("should have only iterated over the articles of the newsroom: %v", a.NewsroomAddress)
+		}
+		if last != nil && a.IndexedTimestamp.After(last.IndexedTimestamp) {
+			t.Errorf("should have iterated over the most recently indexed articles first")
+		}
+		last = a
+		count++
+	}
+	if it.Err() != nil || count != 5 {
+		t.Errorf("should have iterated over the articles: %v: err: %v", count, it.Err())
+	}
+}
+
+func testUpdateNewsroom(t *testing.T, persister newsroom.Persister) {
+	nr := createNewsroom(t, persister, &newsroom.Meta{Index: true})
+
+	nr.Name = "Renamed"
+	nr.Meta = &newsroom.Meta{Claim: true}
+	if err := persister.UpdateNewsroom(nr); err != nil {
+		t.Fatalf("should have updated the newsroom: err: %v", err)
+	}
+
+	found, err := persister.NewsroomByID(nr.ID)
+	if err != nil {
+		t.Fatalf("should have found the newsroom: err: %v", err)
+	}
+	if found.Name != "Renamed" || !reflect.DeepEqual(found.Meta, nr.Meta) {
+		t.Errorf("should have updated the newsroom: %v", found)
+	}
+
+	newsrooms, err := persister.Newsrooms()
+	if err != nil {
+		t.Fatalf("should have returned the newsrooms: err: %v", err)
+	}
+	listed := false
+	for _, n := range newsrooms {
+		if n != nil && n.ID == nr.ID {
+			listed = n.Name == "Renamed"
+		}
+	}
+	if !listed {
+		t.Errorf("should have listed the updated newsroom")
+	}
+}
+
+// createNewsroom creates a newsroom with a random address and the given meta
+func createNewsroom(t *testing.T, persister newsroom.Persister, meta *newsroom.Meta) *newsroom.Newsroom {
+	nr := &newsroom.Newsroom{
+		Name:    "Newsroom " + randomHex(4),
+		Address: randomAddress(),
+		Meta:    meta,
+	}
+	if err := persister.CreateNewsroom(nr); err != nil {
+		t.Fatalf("should have created the newsroom: err: %v", err)
+	}
+	if nr.ID == 0 {
+		t.Fatalf("should have set the id of the newsroom")
+	}
+	return nr
+}
+
+// addArticles adds count articles to the newsroom, each set up by setup if not nil
+func addArticles(t *testing.T, persister newsroom.Persister, newsroomID uint,
+	setup func(i int, a *carticle.Article), count int) {
+	for i := 0; i < count; i++ {
+		a := &carticle.Article{
+			ArticleMetadata:  carticle.Metadata{CanonicalURL: randomURL()},
+			IndexedTimestamp: suiteTime(),
+		}
+		if setup != nil {
+			setup(i, a)
+		}
+		if err := persister.AddArticle(newsroomID, a); err != nil {
+			t.Fatalf("should have added the article: err: %v", err)
+		}
+	}
+}
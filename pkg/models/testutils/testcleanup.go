@@ -7,6 +7,8 @@ import (
 	_ "github.com/jinzhu/gorm/dialects/postgres" // need the driver
 )
 
+const cleanupHookName = "cleanupHook"
+
 // DeleteCreatedEntities sets up a hook to clean up the db after the test
 func DeleteCreatedEntities(db *gorm.DB) func() {
 	type entity struct {
@@ -15,7 +17,7 @@ func DeleteCreatedEntities(db *gorm.DB) func() {
 		key     interface{}
 	}
 	var entries []entity
-	hookName := "cleanupHook"
+	hookName := cleanupHookName
 
 	db.Callback().Create().After("gorm:create").Register(hookName, func(scope *gorm.Scope) {
 		fmt.Printf("Inserted entities of %s with %s=%v\n", scope.TableName(), scope.PrimaryKey(), scope.PrimaryKeyValue())
@@ -43,3 +45,16 @@ func DeleteCreatedEntities(db *gorm.DB) func() {
 		}
 	}
 }
+
+// RegisterCreatedEntities adds entities saved without the gorm create callbacks, such as
+// by raw inserts, to the clean up set up by DeleteCreatedEntities. Each value is a model
+// with its primary key set.
+func RegisterCreatedEntities(db *gorm.DB, values ...interface{}) {
+	hook := db.Callback().Create().Get(cleanupHookName)
+	if hook == nil {
+		return
+	}
+	for _, value := range values {
+		hook(db.NewScope(value))
+	}
+}
@@ -0,0 +1,61 @@
+// Package unitofwork runs work across the article and newsroom persisters in a
+// single transaction
+package unitofwork
+
+import (
+	"github.com/jinzhu/gorm"
+
+	"github.com/joincivil/go-common-priv/pkg/models/article"
+	"github.com/joincivil/go-common-priv/pkg/models/newsroom"
+	gormutils "github.com/joincivil/go-common-priv/pkg/utils/gorm"
+)
+
+// Persisters are the persisters bound to a transaction
+type Persisters struct {
+	Articles  *article.GormPGPersister
+	Newsrooms *newsroom.GormPGPersister
+	// Tx is the transaction, for nesting further units of work with Persisters.WithTx
+	Tx *gorm.DB
+}
+
+// NewPersisters returns the persisters using the given db or transaction. Set the
+// options of the persisters, such as Articles.RequireContentHashMatch, on the result and
+// run the work with Persisters.WithTx to keep them in the transaction.
+func NewPersisters(db *gorm.DB) *Persisters {
+	return &Persisters{
+		Articles:  &article.GormPGPersister{DB: db},
+		Newsrooms: &newsroom.GormPGPersister{DB: db},
+		Tx:        db,
+	}
+}
+
+// bind returns copies of the persisters using the given transaction
+func (p *Persisters) bind(tx *gorm.DB) *Persisters {
+	bound := NewPersisters(tx)
+	bound.Articles.RequireContentHashMatch = p.Articles.RequireContentHashMatch
+	return bound
+}
+
+// WithTx runs fn with persisters bound to one transaction on db, which is committed if fn
+// returns nil and rolled back otherwise. If db is already in a transaction, fn runs in a
+// savepoint. The transaction is retried on serialization failures and deadlocks, so fn
+// must be safe to run more than once. See gormutils.WithTxOptions.
+func WithTx(db *gorm.DB, fn func(p *Persisters) error) error {
+	return WithTxOptions(db, nil, fn)
+}
+
+// WithTxOptions is WithTx with transaction options
+func WithTxOptions(db *gorm.DB, opts *gormutils.TxOptions, fn func(p *Persisters) error) error {
+	return gormutils.WithTxOptions(db, opts, func(tx *gorm.DB) error {
+		return fn(NewPersisters(tx))
+	})
+}
+
+// WithTx runs fn with the persisters bound to a transaction on their db, keeping their
+// options. If the persisters are already in a transaction, fn runs in a savepoint of it,
+// so an error only rolls back the work done by fn.
+func (p *Persisters) WithTx(fn func(p *Persisters) error) error {
+	return gormutils.WithTx(p.Tx, func(tx *gorm.DB) error {
+		return fn(p.bind(tx))
+	})
+}
@@ -0,0 +1,128 @@
+package unitofwork_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+
+	"github.com/joincivil/go-common-priv/pkg/models/article"
+	"github.com/joincivil/go-common-priv/pkg/models/newsroom"
+	"github.com/joincivil/go-common-priv/pkg/models/testutils"
+	"github.com/joincivil/go-common-priv/pkg/models/unitofwork"
+	gormutils "github.com/joincivil/go-common-priv/pkg/utils/gorm"
+	carticle "github.com/joincivil/go-common/pkg/article"
+)
+
+func TestIsRetryable(t *testing.T) {
+	serialization := &pq.Error{Code: "40001"}
+	if !gormutils.IsRetryable(errors.Wrap(serialization, "error saving")) {
+		t.Errorf("should have retried a serialization failure")
+	}
+	if !gormutils.IsRetryable(&pq.Error{Code: "40P01"}) {
+		t.Errorf("should have retried a deadlock")
+	}
+	if gormutils.IsRetryable(&pq.Error{Code: "23505"}) {
+		t.Errorf("should not have retried a unique violation")
+	}
+	if gormutils.IsRetryable(errors.New("not a pq error")) {
+		t.Errorf("should not have retried other errors")
+	}
+}
+
+func TestWithTx(t *testing.T) {
+	creds := testutils.GetTestDBConnection()
+	pg, err := newsroom.NewGormPGPersister(creds.Host, creds.Port, creds.User, creds.Password, creds.Dbname)
+
+	if err != nil {
+		fmt.Println(err)
+		t.Errorf("threw an error making the persister")
+	}
+
+	testutils.MigrateModels(pg.DB) // nolint: errcheck
+
+	defer pg.DB.Close()
+
+	cleaner := testutils.DeleteCreatedEntities(pg.DB)
+	defer cleaner()
+
+	newsroomAddr := "0x9c722B8AC728aDd7780a66017e8daDBa530EE261"
+	rollbackErr := errors.New("roll it back")
+
+	err = unitofwork.WithTx(pg.DB, func(p *unitofwork.Persisters) error {
+		nr := &newsroom.Newsroom{Name: "Rolled back newsroom", Address: newsroomAddr}
+		if err := p.Newsrooms.CreateNewsroom(nr); err != nil {
+			return err
+		}
+		return rollbackErr
+	})
+	if err != rollbackErr {
+		t.Errorf("should have returned the error of the callback: err: %v", err)
+	}
+	if _, err := pg.NewsroomByAddress(newsroomAddr); err == nil {
+		t.Errorf("should have rolled back the newsroom")
+	}
+
+	nr := &newsroom.Newsroom{Name: "Unit of work newsroom", Address: newsroomAddr}
+	narticle := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{CanonicalURL: "https://newstuff.bz/unitofwork"},
+		NewsroomAddress: newsroomAddr,
+	}
+	nested := &carticle.Article{
+		ArticleMetadata: carticle.Metadata{CanonicalURL: "https://newstuff.bz/unitofworknested"},
+		NewsroomAddress: newsroomAddr,
+	}
+
+	err = unitofwork.WithTx(pg.DB, func(p *unitofwork.Persisters) error {
+		if err := p.Newsrooms.CreateNewsroom(nr); err != nil {
+			return err
+		}
+		if err := p.Articles.CreateArticle(narticle); err != nil {
+			return err
+		}
+
+		// The savepoint is rolled back without failing the transaction
+		err := p.WithTx(func(sp *unitofwork.Persisters) error {
+			if err := sp.Articles.CreateArticle(nested); err != nil {
+				return err
+			}
+			return rollbackErr
+		})
+		if err != rollbackErr {
+			return errors.Errorf("should have returned the savepoint error: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("should have committed the unit of work: err: %v", err)
+	}
+
+	if _, err := pg.NewsroomByAddress(newsroomAddr); err != nil {
+		t.Errorf("should have committed the newsroom: err: %v", err)
+	}
+	articles := &article.GormPGPersister{DB: pg.DB}
+	if _, err := articles.ArticleByID(narticle.ID); err != nil {
+		t.Errorf("should have committed the article: err: %v", err)
+	}
+	if _, err := articles.ArticleByID(nested.ID); err == nil {
+		t.Errorf("should have rolled back the article in the savepoint")
+	}
+
+	persisters := unitofwork.NewPersisters(pg.DB)
+	persisters.Articles.RequireContentHashMatch = true
+	err = persisters.WithTx(func(p *unitofwork.Persisters) error {
+		if !gormutils.InTransaction(p.Tx) || !p.Articles.RequireContentHashMatch {
+			return errors.New("should have kept the options in the transaction")
+		}
+		return p.WithTx(func(sp *unitofwork.Persisters) error {
+			if !sp.Articles.RequireContentHashMatch {
+				return errors.New("should have kept the options in the savepoint")
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		t.Errorf("should have bound the persisters: err: %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package gorm
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jinzhu/gorm"
+)
+
+// TimeoutError is returned when a query is aborted because the deadline of its
+// context passed
+type TimeoutError struct {
+	Err error
+}
+
+// Error implements error
+func (e *TimeoutError) Error() string {
+	return "query timed out: " + e.Err.Error()
+}
+
+// Cause returns the underlying error, for errors.Cause
+func (e *TimeoutError) Cause() error {
+	return e.Err
+}
+
+// IsTimeout returns true if the cause of the error is a TimeoutError
+func IsTimeout(err error) bool {
+	for err != nil {
+		if _, ok := err.(*TimeoutError); ok {
+			return true
+		}
+		cause, ok := err.(interface{ Cause() error })
+		if !ok {
+			return false
+		}
+		err = cause.Cause()
+	}
+	return false
+}
+
+// ContextError maps an error from a query run with the context. If the deadline of
+// the context passed, it returns a TimeoutError. If the context was cancelled, it
+// returns context.Canceled. Otherwise it returns the error as is.
+func ContextError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		if _, ok := err.(*TimeoutError); ok {
+			return err
+		}
+		return &TimeoutError{Err: err}
+	case context.Canceled:
+		return context.Canceled
+	}
+	return err
+}
+
+// WithContext runs fn in a transaction on db that is bound to the context, committing if
+// fn returns nil and rolling back otherwise. Cancelling the context aborts the query in
+// flight and rolls the transaction back. The transaction is begun with BeginTx, so it
+// keeps the callbacks, logger and log mode of db. It is not retried, errors are returned
+// as is, use ContextError to map them.
+//
+// If db is already in a transaction, fn runs in a savepoint of it and its statements are
+// bound to the context the transaction was begun with, if any.
+func WithContext(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	return WithTxOptions(db, &TxOptions{MaxAttempts: 1, Context: ctx}, fn)
+}
+
+// InTransaction returns true if db is in a transaction
+func InTransaction(db *gorm.DB) bool {
+	_, ok := db.CommonDB().(*sql.Tx)
+	return ok
+}
@@ -0,0 +1,95 @@
+package gorm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite" // need the sqlite driver
+
+	gormutils "github.com/joincivil/go-common-priv/pkg/utils/gorm"
+)
+
+type countingLogger struct {
+	count int
+}
+
+func (l *countingLogger) Print(v ...interface{}) {
+	l.count++
+}
+
+type contextTestRow struct {
+	ID   uint
+	Name string
+}
+
+func TestWithContextKeepsSettings(t *testing.T) {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("should have opened the db: err: %v", err)
+	}
+	defer db.Close()
+	// Each connection to :memory: is a separate db
+	db.DB().SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&contextTestRow{}).Error; err != nil {
+		t.Fatalf("should have created the table: err: %v", err)
+	}
+
+	logger := &countingLogger{}
+	db.SetLogger(logger)
+	db.LogMode(true)
+	queries := 0
+	db.Callback().Query().After("gorm:query").Register("test:count_queries", func(scope *gorm.Scope) {
+		queries++
+	})
+
+	err = gormutils.WithContext(context.Background(), db, func(tx *gorm.DB) error {
+		if !gormutils.InTransaction(tx) {
+			t.Errorf("should have run in a transaction")
+		}
+		if err := tx.Create(&contextTestRow{Name: "test"}).Error; err != nil {
+			return err
+		}
+		rows := []contextTestRow{}
+		if err := tx.Find(&rows).Error; err != nil || len(rows) != 1 {
+			t.Errorf("should have found the row: %v: err: %v", rows, err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("should have committed the transaction: err: %v", err)
+	}
+	if queries != 1 {
+		t.Errorf("should have kept the registered callbacks: %v", queries)
+	}
+	if logger.count == 0 {
+		t.Errorf("should have kept the logger and log mode")
+	}
+
+	count := 0
+	if err := db.Model(&contextTestRow{}).Count(&count).Error; err != nil || count != 1 {
+		t.Errorf("should have committed the row: %v: err: %v", count, err)
+	}
+}
+
+func TestWithContextCancelled(t *testing.T) {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("should have opened the db: err: %v", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	called := false
+	err = gormutils.WithContext(ctx, db, func(tx *gorm.DB) error {
+		called = true
+		return nil
+	})
+	if gormutils.ContextError(ctx, err) != context.Canceled {
+		t.Errorf("should have returned context.Canceled: err: %v", err)
+	}
+	if called {
+		t.Errorf("should not have run fn with a cancelled context")
+	}
+}
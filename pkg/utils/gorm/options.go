@@ -0,0 +1,108 @@
+package gorm
+
+import (
+	"time"
+)
+
+const (
+	// DefaultMaxOpenConns is the default maximum number of open connections in the pool
+	DefaultMaxOpenConns = 5
+	// DefaultMaxIdleConns is the default maximum number of idle connections in the pool
+	DefaultMaxIdleConns = 3
+	// DefaultConnMaxLifetime is the default maximum time a connection is reused
+	DefaultConnMaxLifetime = 30 * time.Minute
+	// DefaultDialect is the default gorm dialect of the connections
+	DefaultDialect = "postgres"
+)
+
+// Logger is the interface of the gorm logger, such as gorm.Logger
+type Logger interface {
+	Print(v ...interface{})
+}
+
+// Options are the options of a connection pool opened by the constructors
+type Options struct {
+	// MaxOpenConns is the maximum number of open connections, unlimited if 0
+	MaxOpenConns int
+	// MaxIdleConns is the maximum number of idle connections, none are kept if 0
+	MaxIdleConns int
+	// ConnMaxLifetime is the maximum time a connection is reused, forever if 0
+	ConnMaxLifetime time.Duration
+	// ConnMaxIdleTime is the maximum time a connection is idle before it is closed,
+	// forever if 0
+	ConnMaxIdleTime time.Duration
+	// Logger replaces the default gorm logger if set
+	Logger Logger
+	// LogMode logs every query if true, only errors if false, uses the gorm default if nil
+	LogMode *bool
+	// Dialect is the gorm dialect and driver name, such as "postgres" or "cloudsqlpostgres"
+	Dialect string
+}
+
+// Option sets an option of a connection pool opened by the constructors
+type Option func(opts *Options)
+
+// NewOptions returns the default Options with the given options applied
+func NewOptions(opts ...Option) *Options {
+	options := &Options{
+		MaxOpenConns:    DefaultMaxOpenConns,
+		MaxIdleConns:    DefaultMaxIdleConns,
+		ConnMaxLifetime: DefaultConnMaxLifetime,
+		Dialect:         DefaultDialect,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// WithMaxOpenConns sets the maximum number of open connections, unlimited if 0
+func WithMaxOpenConns(n int) Option {
+	return func(opts *Options) {
+		opts.MaxOpenConns = n
+	}
+}
+
+// WithMaxIdleConns sets the maximum number of idle connections, none are kept if 0
+func WithMaxIdleConns(n int) Option {
+	return func(opts *Options) {
+		opts.MaxIdleConns = n
+	}
+}
+
+// WithConnMaxLifetime sets the maximum time a connection is reused, forever if 0
+func WithConnMaxLifetime(d time.Duration) Option {
+	return func(opts *Options) {
+		opts.ConnMaxLifetime = d
+	}
+}
+
+// WithConnMaxIdleTime sets the maximum time a connection is idle before it is closed,
+// forever if 0
+func WithConnMaxIdleTime(d time.Duration) Option {
+	return func(opts *Options) {
+		opts.ConnMaxIdleTime = d
+	}
+}
+
+// WithLogger replaces the default gorm logger
+func WithLogger(logger Logger) Option {
+	return func(opts *Options) {
+		opts.Logger = logger
+	}
+}
+
+// WithLogMode logs every query if enabled, only errors otherwise
+func WithLogMode(enabled bool) Option {
+	return func(opts *Options) {
+		opts.LogMode = &enabled
+	}
+}
+
+// WithDialect sets the gorm dialect and driver name, such as "cloudsqlpostgres". The
+// dialect and driver have to be registered.
+func WithDialect(dialect string) Option {
+	return func(opts *Options) {
+		opts.Dialect = dialect
+	}
+}
@@ -0,0 +1,38 @@
+package gorm_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	gormutils "github.com/joincivil/go-common-priv/pkg/utils/gorm"
+)
+
+func TestNewOptions(t *testing.T) {
+	opts := gormutils.NewOptions()
+	if opts.MaxOpenConns != 5 || opts.MaxIdleConns != 3 || opts.ConnMaxLifetime != 30*time.Minute {
+		t.Errorf("should have kept the previous pool defaults: %+v", opts)
+	}
+	if opts.Dialect != "postgres" || opts.Logger != nil || opts.LogMode != nil {
+		t.Errorf("should have defaulted to postgres with the gorm logger: %+v", opts)
+	}
+
+	logger := gorm.Logger{}
+	opts = gormutils.NewOptions(
+		gormutils.WithMaxOpenConns(50),
+		gormutils.WithMaxIdleConns(10),
+		gormutils.WithConnMaxLifetime(time.Hour),
+		gormutils.WithConnMaxIdleTime(time.Minute),
+		gormutils.WithLogger(logger),
+		gormutils.WithLogMode(true),
+		gormutils.WithDialect("cloudsqlpostgres"),
+	)
+	if opts.MaxOpenConns != 50 || opts.MaxIdleConns != 10 || opts.ConnMaxLifetime != time.Hour ||
+		opts.ConnMaxIdleTime != time.Minute {
+		t.Errorf("should have set the pool options: %+v", opts)
+	}
+	if opts.Logger != logger || opts.LogMode == nil || !*opts.LogMode || opts.Dialect != "cloudsqlpostgres" {
+		t.Errorf("should have set the logger and dialect: %+v", opts)
+	}
+}
@@ -0,0 +1,131 @@
+package gorm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// DefaultTxMaxAttempts is the number of times WithTx runs a transaction that fails
+	// with a serialization failure or deadlock
+	DefaultTxMaxAttempts = 3
+
+	txRetryBackoff     = 20 * time.Millisecond
+	savepointDepthName = "gormutils:savepoint_depth"
+
+	pqSerializationFailure = "40001"
+	pqDeadlockDetected     = "40P01"
+)
+
+// TxOptions are the options for WithTxOptions
+type TxOptions struct {
+	// MaxAttempts is the number of times the transaction is run if it fails with a
+	// serialization failure or deadlock. Defaults to DefaultTxMaxAttempts if 0.
+	MaxAttempts int
+	// Serializable runs the transaction with the serializable isolation level
+	Serializable bool
+	// Context binds the transaction to the context if set, see WithContext. It does not
+	// apply to savepoints.
+	Context context.Context
+}
+
+// WithTx runs fn in a transaction on db with the default options. See WithTxOptions.
+func WithTx(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	return WithTxOptions(db, nil, fn)
+}
+
+// WithTxOptions runs fn in a transaction on db, committing if fn returns nil and rolling
+// back otherwise. If db is already in a transaction, fn runs in a savepoint instead, so
+// an error only rolls back the changes made by fn and the outer transaction can continue.
+//
+// A transaction that fails with a serialization failure or deadlock is retried, so fn must
+// be safe to run more than once. Savepoints are not retried, the error is returned to the
+// outer transaction which retries as a whole.
+func WithTxOptions(db *gorm.DB, opts *TxOptions, fn func(tx *gorm.DB) error) error {
+	if InTransaction(db) {
+		return withSavepoint(db, fn)
+	}
+
+	if opts == nil {
+		opts = &TxOptions{}
+	}
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultTxMaxAttempts
+	}
+
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		err = runTx(db, opts, fn)
+		if err == nil || !IsRetryable(err) {
+			return err
+		}
+		if attempt < maxAttempts {
+			time.Sleep(time.Duration(attempt) * txRetryBackoff)
+		}
+	}
+	return err
+}
+
+// IsRetryable returns true if the cause of the error is a postgres serialization failure
+// or deadlock, after which the transaction can be retried
+func IsRetryable(err error) bool {
+	pqErr, ok := errors.Cause(err).(*pq.Error)
+	if !ok {
+		return false
+	}
+	return pqErr.Code == pqSerializationFailure || pqErr.Code == pqDeadlockDetected
+}
+
+func runTx(db *gorm.DB, opts *TxOptions, fn func(tx *gorm.DB) error) error {
+	var tx *gorm.DB
+	if opts.Context != nil {
+		tx = db.BeginTx(opts.Context, nil)
+	} else {
+		tx = db.Begin()
+	}
+	if tx.Error != nil {
+		return tx.Error
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	if opts.Serializable {
+		if err := tx.Exec("SET TRANSACTION ISOLATION LEVEL SERIALIZABLE").Error; err != nil {
+			return err
+		}
+	}
+
+	if err := fn(tx.Set(savepointDepthName, 0)); err != nil {
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func withSavepoint(tx *gorm.DB, fn func(tx *gorm.DB) error) error {
+	depth := 0
+	if value, ok := tx.Get(savepointDepthName); ok {
+		depth, _ = value.(int)
+	}
+	depth++
+	name := fmt.Sprintf("gormutils_sp_%d", depth)
+
+	if err := tx.Exec("SAVEPOINT " + name).Error; err != nil {
+		return err
+	}
+
+	if err := fn(tx.Set(savepointDepthName, depth)); err != nil {
+		if rbErr := tx.Exec("ROLLBACK TO SAVEPOINT " + name).Error; rbErr != nil {
+			return errors.Wrap(rbErr, "error rolling back to savepoint")
+		}
+		return err
+	}
+
+	return tx.Exec("RELEASE SAVEPOINT " + name).Error
+}
@@ -1,11 +1,8 @@
 package gorm
 
 import (
-	"fmt"
 	"time"
 
-	"github.com/pkg/errors"
-
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/postgres" // need the driver
 )
@@ -14,22 +11,35 @@ import (
 // Postgresql database parameters
 func NewGormPGConnection(host string, port int, user string, password string,
 	dbname string, maxOpenConns int, maxIdleConns int, connMaxLifetime time.Duration) (*gorm.DB, error) {
-	psqlInfo := fmt.Sprintf(
-		"host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		host,
-		port,
-		user,
-		password,
-		dbname,
+	return NewGormPGConnectionWithConfig(
+		NewConfig(host, port, user, password, dbname),
+		WithMaxOpenConns(maxOpenConns),
+		WithMaxIdleConns(maxIdleConns),
+		WithConnMaxLifetime(connMaxLifetime),
 	)
-	db, err := gorm.Open("postgres", psqlInfo)
+}
+
+// NewGormPGConnectionWithConfig is a helper function to create a new Gorm conn pool given
+// the Postgresql connection config. The pool uses the defaults in NewOptions unless set
+// by opts.
+func NewGormPGConnectionWithConfig(cfg *Config, opts ...Option) (*gorm.DB, error) {
+	options := NewOptions(opts...)
+	db, err := cfg.open(options.Dialect)
 	if err != nil {
-		return nil, errors.Wrap(err, "error connecting to gorm")
+		return nil, err
 	}
 
-	db.DB().SetMaxOpenConns(maxOpenConns)
-	db.DB().SetMaxIdleConns(maxIdleConns)
-	db.DB().SetConnMaxLifetime(connMaxLifetime)
+	db.DB().SetMaxOpenConns(options.MaxOpenConns)
+	db.DB().SetMaxIdleConns(options.MaxIdleConns)
+	db.DB().SetConnMaxLifetime(options.ConnMaxLifetime)
+	db.DB().SetConnMaxIdleTime(options.ConnMaxIdleTime)
+
+	if options.Logger != nil {
+		db.SetLogger(options.Logger)
+	}
+	if options.LogMode != nil {
+		db.LogMode(*options.LogMode)
+	}
 
 	return db, nil
 }